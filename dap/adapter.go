@@ -7,14 +7,14 @@ import (
 	"io"
 	"log"
 	"os"
-	"path/filepath"
-	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
 )
 
 type DebugAdapter struct {
@@ -23,19 +23,62 @@ type DebugAdapter struct {
 	seq      int
 	seqMutex sync.Mutex
 
+	// Client conventions, from initialize
+	columnsStartAt1 bool
+
 	// Goja runtime
 	vm          *goja.Runtime
-	debugger    *goja.Debugger
+	debugger    *engineDebugger
+	handler     func(*debugState) debugCommand // installed on debugger, see swapDebugHandler
+	stepMode    bool                           // step mode set on debugger
+	frames      frameInspector                 // reads paused frames, see engine.go
+	safeEval    *sideEffectFree                // built-ins hovers and watches may call, see sideeffects.go
 	program     string
 	sourceCode  string
 	sourceLines []string
+	launchArgs  LaunchRequestArguments // kept for restart
 
 	// Debug state
-	running     bool
-	terminated  bool
-	breakpoints map[string][]int // filename -> line numbers
-	bpIDCounter int
-	bpMap       map[int]*Breakpoint // breakpoint ID -> breakpoint
+	running       bool
+	terminated    bool
+	restarting    bool
+	executionDone chan struct{}    // closed when the program run ends
+	breakpoints   map[string][]int // filename -> source breakpoint IDs
+	bpIDCounter   int
+	bpMap         map[int]*Breakpoint        // breakpoint ID -> breakpoint
+	bpOptions     map[int]SourceBreakpoint   // breakpoint ID -> requested options
+	bpHits        map[int]int                // breakpoint ID -> hit count
+	bpReported    map[int]bool               // breakpoint ID -> an evaluation problem was reported
+	functionBPs   map[int]FunctionBreakpoint // breakpoint ID -> function breakpoint
+	functionStops map[int][]file.Position    // breakpoint ID -> where a function breakpoint stops
+	bpMutex       sync.Mutex
+	lastPoints    []executionPoint          // last position of every frame, for source breakpoints
+	stops         atomic.Pointer[stopTable] // where execution can stop, see breakpoints.go
+
+	// Loaded scripts
+	scriptASTs       map[string]*ast.Program   // filename -> parsed source
+	loadedScripts    []*loadedScript           // every script seen in the runtime
+	evalScripts      map[uintptr]*loadedScript // compiled eval code -> its script
+	sourceRefCounter int
+	consoleScripts   int
+	scriptsMutex     sync.Mutex
+
+	// Exceptions
+	exceptionFilters map[string]bool // enabled exception breakpoint filters
+	throwSites       []*throwSite    // throw statements the filters stop at
+	exception        *exceptionState // exception we are stopped on, if any
+	uncaughtReported bool
+
+	// Step into targets
+	stepInCalls   []callTarget // calls offered by the last stepInTargets request
+	stepInFrameID int
+	stepTarget    *stepTarget // targeted stepIn in progress
+
+	// Data breakpoints
+	dataObjects    []*goja.Object        // objects referenced by data breakpoint IDs
+	dataWatches    map[string]*dataWatch // data ID -> watched property
+	dataHit        *dataHit              // access that requested the current pause
+	inDebugHandler bool
 
 	// Thread simulation (goja is single-threaded)
 	threadID int
@@ -46,99 +89,39 @@ type DebugAdapter struct {
 
 	// Stack frames
 	currentFrameID int
-	frameMap       map[int]*goja.StackFrame
+	frameMap       map[int]*goja.StackFrame // frame ID -> frame, for the current pause
+	stackFrames    []goja.StackFrame        // call stack of the current pause
 
 	// Synchronization
 	debugStateMutex sync.Mutex
 	waitingForCmd   bool
-	nextCommand     goja.DebugCommand
+	nextCommand     debugCommand
 	commandReady    chan struct{}
-
-	// Enhanced features
-	functionScopes  map[string][]string // function name -> detected variable names
-	currentFunction string              // track current function for variable detection
 }
 
 func NewDebugAdapter(reader io.Reader, writer io.Writer) *DebugAdapter {
 	return &DebugAdapter{
-		reader:         bufio.NewReader(reader),
-		writer:         writer,
-		seq:            1,
-		breakpoints:    make(map[string][]int),
-		bpMap:          make(map[int]*Breakpoint),
-		varRefMap:      make(map[int]interface{}),
-		frameMap:       make(map[int]*goja.StackFrame),
-		threadID:       1,
-		commandReady:   make(chan struct{}),
-		nextCommand:    goja.DebugContinue,
-		functionScopes: make(map[string][]string),
-	}
-}
-
-// Parse source code to detect function parameters and local variables
-func (da *DebugAdapter) parseSourceForVariables() {
-	// Regular expressions to find functions and their variables
-	funcRegex := regexp.MustCompile(`function\s+(\w+)\s*\(([^)]*)\)`)
-	varRegex := regexp.MustCompile(`\b(var|let|const)\s+(\w+)`)
-
-	currentFunc := ""
-	inFunction := false
-	braceCount := 0
-
-	for i, line := range da.sourceLines {
-		// Check for function declaration
-		if matches := funcRegex.FindStringSubmatch(line); len(matches) > 0 {
-			currentFunc = matches[1]
-			params := matches[2]
-
-			// Parse parameters
-			if params != "" {
-				paramList := strings.Split(params, ",")
-				da.functionScopes[currentFunc] = []string{}
-				for _, param := range paramList {
-					param = strings.TrimSpace(param)
-					if param != "" {
-						da.functionScopes[currentFunc] = append(da.functionScopes[currentFunc], param)
-					}
-				}
-			} else {
-				da.functionScopes[currentFunc] = []string{}
-			}
-			inFunction = true
-			braceCount = 0
-		}
-
-		// Track braces to know when we exit a function
-		braceCount += strings.Count(line, "{") - strings.Count(line, "}")
-
-		// Look for variable declarations inside functions
-		if inFunction && currentFunc != "" {
-			if matches := varRegex.FindAllStringSubmatch(line, -1); len(matches) > 0 {
-				for _, match := range matches {
-					varName := match[2]
-					// Add to function scope if not already there
-					found := false
-					for _, v := range da.functionScopes[currentFunc] {
-						if v == varName {
-							found = true
-							break
-						}
-					}
-					if !found {
-						da.functionScopes[currentFunc] = append(da.functionScopes[currentFunc], varName)
-					}
-				}
-			}
-		}
-
-		// Check if we've exited the function
-		if inFunction && braceCount <= 0 && i > 0 {
-			inFunction = false
-			currentFunc = ""
-		}
+		reader:          bufio.NewReader(reader),
+		writer:          writer,
+		seq:             1,
+		columnsStartAt1: true,
+		breakpoints:     make(map[string][]int),
+		bpMap:           make(map[int]*Breakpoint),
+		bpOptions:       make(map[int]SourceBreakpoint),
+		bpHits:          make(map[int]int),
+		bpReported:      make(map[int]bool),
+		functionBPs:     make(map[int]FunctionBreakpoint),
+		functionStops:   make(map[int][]file.Position),
+		scriptASTs:      make(map[string]*ast.Program),
+		evalScripts:     make(map[uintptr]*loadedScript),
+		dataWatches:     make(map[string]*dataWatch),
+		varRefMap:       make(map[int]interface{}),
+		frameMap:        make(map[int]*goja.StackFrame),
+		threadID:        1,
+		commandReady:    make(chan struct{}),
+		nextCommand:     debugContinue,
+		frames:          newFrameInspector(),
 	}
-
-	log.Printf("Detected function scopes: %+v", da.functionScopes)
 }
 
 func (da *DebugAdapter) nextSeq() int {
@@ -270,8 +253,22 @@ func (da *DebugAdapter) handleRequest(req *Request) {
 		da.handleInitialize(req)
 	case "launch":
 		da.handleLaunch(req)
+	case "restart":
+		da.handleRestart(req)
 	case "setBreakpoints":
 		da.handleSetBreakpoints(req)
+	case "breakpointLocations":
+		da.handleBreakpointLocations(req)
+	case "setFunctionBreakpoints":
+		da.handleSetFunctionBreakpoints(req)
+	case "setExceptionBreakpoints":
+		da.handleSetExceptionBreakpoints(req)
+	case "dataBreakpointInfo":
+		da.handleDataBreakpointInfo(req)
+	case "setDataBreakpoints":
+		da.handleSetDataBreakpoints(req)
+	case "exceptionInfo":
+		da.handleExceptionInfo(req)
 	case "configurationDone":
 		da.handleConfigurationDone(req)
 	case "threads":
@@ -284,6 +281,16 @@ func (da *DebugAdapter) handleRequest(req *Request) {
 		da.handleVariables(req)
 	case "evaluate":
 		da.handleEvaluate(req)
+	case "source":
+		da.handleSource(req)
+	case "loadedSources":
+		da.handleLoadedSources(req)
+	case "completions":
+		da.handleCompletions(req)
+	case "setVariable":
+		da.handleSetVariable(req)
+	case "setExpression":
+		da.handleSetExpression(req)
 	case "continue":
 		da.handleContinue(req)
 	case "next":
@@ -292,6 +299,14 @@ func (da *DebugAdapter) handleRequest(req *Request) {
 		da.handleStepIn(req)
 	case "stepOut":
 		da.handleStepOut(req)
+	case "stepInTargets":
+		da.handleStepInTargets(req)
+	case "gotoTargets":
+		da.handleGotoTargets(req)
+	case "goto":
+		da.handleGoto(req)
+	case "restartFrame":
+		da.handleRestartFrame(req)
 	case "pause":
 		da.handlePause(req)
 	case "disconnect":
@@ -307,12 +322,34 @@ func (da *DebugAdapter) handleRequest(req *Request) {
 func (da *DebugAdapter) handleInitialize(req *Request) {
 	log.Printf(">>> Initialize request - setting up debug session")
 
+	var args InitializeRequestArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+	da.columnsStartAt1 = args.ColumnsStartAt1 == nil || *args.ColumnsStartAt1
+
 	capabilities := Capabilities{
-		SupportsConfigurationDoneRequest: true,
-		SupportsConditionalBreakpoints:   false,
-		SupportsEvaluateForHovers:        true,
-		SupportsSetVariable:              false,
-		SupportsTerminateRequest:         true,
+		SupportsConfigurationDoneRequest:   true,
+		SupportsFunctionBreakpoints:        true,
+		SupportsConditionalBreakpoints:     true,
+		SupportsHitConditionalBreakpoints:  true,
+		SupportsLogPoints:                  true,
+		SupportsEvaluateForHovers:          true,
+		SupportsSetVariable:                true,
+		SupportsSetExpression:              true,
+		SupportsRestartFrame:               false,
+		SupportsStepInTargetsRequest:       true,
+		SupportsGotoTargetsRequest:         false,
+		SupportsTerminateRequest:           true,
+		SupportsExceptionInfoRequest:       true,
+		SupportsCompletionsRequest:         true,
+		SupportsDataBreakpoints:            true,
+		SupportsLoadedSourcesRequest:       true,
+		SupportsBreakpointLocationsRequest: true,
+		SupportsRestartRequest:             true,
+		SupportsDelayedStackTraceLoading:   true,
+		ExceptionBreakpointFilters:         exceptionFilters,
 	}
 
 	da.sendResponse(req.Seq, req.Command, true, capabilities)
@@ -330,29 +367,58 @@ func (da *DebugAdapter) handleLaunch(req *Request) {
 		json.Unmarshal(data, &args)
 	}
 
+	if err := da.launch(args); err != nil {
+		da.sendResponse(req.Seq, req.Command, false, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	da.sendResponse(req.Seq, req.Command, true, nil)
+}
+
+// launch loads the program and creates a runtime to debug it in, with the
+// stored breakpoints applied. A restart launches again with the same
+// arguments.
+func (da *DebugAdapter) launch(args LaunchRequestArguments) error {
+	da.launchArgs = args
 	da.program = args.Program
 	log.Printf(">>> Program to debug: %s", da.program)
 
 	// Read the program file
 	content, err := os.ReadFile(da.program)
 	if err != nil {
-		da.sendResponse(req.Seq, req.Command, false, map[string]string{
-			"error": fmt.Sprintf("Failed to read program: %v", err),
-		})
-		return
+		return fmt.Errorf("Failed to read program: %v", err)
 	}
 
+	// Forget the previous run, if any
+	da.resetRunState()
+
 	da.sourceCode = string(content)
 	da.sourceLines = strings.Split(da.sourceCode, "\n")
 
-	// Parse source to detect variables
-	da.parseSourceForVariables()
-
 	log.Printf("Loaded program %s with %d lines", da.program, len(da.sourceLines))
 
+	if _, err := da.parseScript(da.program, da.sourceCode); err != nil {
+		log.Printf("Failed to parse %s: %v", da.program, err)
+	}
+	da.addScript(da.program, da.program, da.sourceCode, "")
+
 	// Create runtime and enable debugger
 	da.vm = goja.New()
-	da.debugger = da.vm.EnableDebugger()
+	da.debugger = enableDebugger(da.vm)
+	if !hasDebugger {
+		da.sendEvent("output", map[string]interface{}{
+			"category": "important",
+			"output":   "This goja build has no debugger, the program runs without pausing\n",
+		})
+	}
+	if hasDebugger && !da.frames.canReadFrames() {
+		da.sendEvent("output", map[string]interface{}{
+			"category": "important",
+			"output":   "This goja build can't read frame scopes: parameters and local variables aren't shown, only script and global variables\n",
+		})
+	}
 
 	// Set up console.log
 	console := da.vm.NewObject()
@@ -381,88 +447,19 @@ func (da *DebugAdapter) handleLaunch(req *Request) {
 	da.vm.Set("console", console)
 
 	// Set up debug handler
-	da.debugger.SetHandler(da.debugHandler)
+	da.setDebugHandler(da.debugHandler)
 
 	// Only enable step mode if explicitly requested
 	if args.StopOnEntry {
-		da.nextCommand = goja.DebugStepInto
-		da.debugger.SetStepMode(true)
+		da.nextCommand = debugStepInto
+		da.setStepMode(true)
 	} else {
-		da.nextCommand = goja.DebugContinue
-		da.debugger.SetStepMode(false)
-	}
-
-	da.sendResponse(req.Seq, req.Command, true, nil)
-}
-
-func (da *DebugAdapter) handleSetBreakpoints(req *Request) {
-	var args SetBreakpointsArguments
-	if req.Arguments != nil {
-		data, _ := json.Marshal(req.Arguments)
-		json.Unmarshal(data, &args)
-	}
-
-	// Clear existing breakpoints for this file
-	filename := args.Source.Path
-	if filename == "" {
-		filename = args.Source.Name
-	}
-
-	// Make sure we're using the same filename as the program
-	if filepath.Base(filename) == filepath.Base(da.program) {
-		filename = da.program
-		log.Printf("Normalized filename from %s to %s", args.Source.Path, filename)
-	}
-
-	log.Printf("SetBreakpoints request for file: %s, breakpoints: %d", filename, len(args.Breakpoints))
-
-	// Remove ALL old breakpoints for this file
-	if da.debugger != nil {
-		existingBPs := da.debugger.GetBreakpoints()
-		for _, ebp := range existingBPs {
-			if ebp.SourcePos.Filename == filename {
-				da.debugger.RemoveBreakpoint(ebp.ID())
-			}
-		}
-	}
-
-	// Clear our tracking maps
-	for id, bp := range da.bpMap {
-		if bp.Source.Path == filename {
-			delete(da.bpMap, id)
-		}
-	}
-	da.breakpoints[filename] = []int{}
-
-	// Add new breakpoints
-	var breakpoints []Breakpoint
-
-	for _, sbp := range args.Breakpoints {
-		// Add breakpoint to debugger
-		gojaID := da.debugger.AddBreakpoint(filename, sbp.Line, sbp.Column)
-
-		da.bpIDCounter++
-		bpID := da.bpIDCounter
-
-		bp := Breakpoint{
-			ID:       bpID,
-			Verified: true,
-			Source:   args.Source,
-			Line:     sbp.Line,
-			Column:   sbp.Column,
-		}
-
-		da.bpMap[bpID] = &bp
-		da.breakpoints[filename] = append(da.breakpoints[filename], sbp.Line)
-		breakpoints = append(breakpoints, bp)
-
-		log.Printf("Added breakpoint: file=%s, line=%d, column=%d, gojaID=%d",
-			filename, sbp.Line, sbp.Column, gojaID)
+		da.nextCommand = debugContinue
+		da.setStepMode(false)
 	}
 
-	da.sendResponse(req.Seq, req.Command, true, SetBreakpointsResponseBody{
-		Breakpoints: breakpoints,
-	})
+	da.applyBreakpoints()
+	return nil
 }
 
 func (da *DebugAdapter) handleConfigurationDone(req *Request) {
@@ -471,7 +468,7 @@ func (da *DebugAdapter) handleConfigurationDone(req *Request) {
 	da.sendResponse(req.Seq, req.Command, true, nil)
 
 	// Start execution in a goroutine
-	go da.startExecution()
+	da.runProgram()
 }
 
 func (da *DebugAdapter) handleThreads(req *Request) {
@@ -487,91 +484,6 @@ func (da *DebugAdapter) handleThreads(req *Request) {
 	})
 }
 
-func (da *DebugAdapter) handleStackTrace(req *Request) {
-	var args StackTraceArguments
-	if req.Arguments != nil {
-		data, _ := json.Marshal(req.Arguments)
-		json.Unmarshal(data, &args)
-	}
-
-	// Get call stack
-	stack := da.vm.CaptureCallStack(10, nil)
-
-	var frames []StackFrame
-	da.frameMap = make(map[int]*goja.StackFrame)
-
-	for i, frame := range stack {
-		frameID := i + 1
-		da.frameMap[frameID] = &frame
-
-		funcName := frame.FuncName()
-		if funcName == "" {
-			funcName = "(anonymous)"
-		}
-
-		pos := frame.Position()
-		sf := StackFrame{
-			ID:     frameID,
-			Name:   funcName,
-			Line:   pos.Line,
-			Column: 1,
-			Source: Source{
-				Name: filepath.Base(pos.Filename),
-				Path: pos.Filename,
-			},
-		}
-
-		frames = append(frames, sf)
-	}
-
-	da.sendResponse(req.Seq, req.Command, true, StackTraceResponseBody{
-		StackFrames: frames,
-		TotalFrames: len(frames),
-	})
-}
-
-func (da *DebugAdapter) handleScopes(req *Request) {
-	var args ScopesArguments
-	if req.Arguments != nil {
-		data, _ := json.Marshal(req.Arguments)
-		json.Unmarshal(data, &args)
-	}
-
-	// Create both Local and Global scopes
-	scopes := []Scope{}
-
-	// Local scope
-	da.varRefCounter++
-	localRef := da.varRefCounter
-	da.varRefMap[localRef] = map[string]interface{}{
-		"type":    "local",
-		"frameID": args.FrameID,
-	}
-
-	scopes = append(scopes, Scope{
-		Name:               "Local",
-		VariablesReference: localRef,
-		Expensive:          false,
-	})
-
-	// Global scope
-	da.varRefCounter++
-	globalRef := da.varRefCounter
-	da.varRefMap[globalRef] = map[string]interface{}{
-		"type": "global",
-	}
-
-	scopes = append(scopes, Scope{
-		Name:               "Global",
-		VariablesReference: globalRef,
-		Expensive:          false,
-	})
-
-	da.sendResponse(req.Seq, req.Command, true, ScopesResponseBody{
-		Scopes: scopes,
-	})
-}
-
 func (da *DebugAdapter) handleVariables(req *Request) {
 	var args VariablesArguments
 	if req.Arguments != nil {
@@ -596,11 +508,19 @@ func (da *DebugAdapter) handleVariables(req *Request) {
 			} else if scopeType == "global" {
 				log.Printf("Getting global variables")
 				variables = da.getGlobalVariables()
+			} else if scopeType == "exception" {
+				variables = da.exceptionVariables()
 			}
-		} else if val, ok := scopeInfo.(goja.Value); ok {
+		} else if obj, ok := scopeInfo.(*goja.Object); ok {
 			// It's an object to expand
 			log.Printf("Expanding object properties")
-			variables = da.getObjectProperties(val)
+			variables = da.objectVariables(obj, args)
+		} else if chunk, ok := scopeInfo.(*arrayChunk); ok {
+			log.Printf("Expanding elements %d to %d", chunk.start, chunk.start+chunk.count-1)
+			variables = da.chunkVariables(chunk, args)
+		} else if fs, ok := scopeInfo.(*frameScope); ok {
+			log.Printf("Getting %s scope variables for frame %d", fs.scope.kind, fs.frameID)
+			variables = pageItems(da.scopeVariables(fs), args.Start, args.Count)
 		}
 	} else {
 		log.Printf("WARNING: variablesReference %d not found in map", args.VariablesReference)
@@ -612,35 +532,118 @@ func (da *DebugAdapter) handleVariables(req *Request) {
 	})
 }
 
+// getLocalVariables lists the parameters, locals and this of a frame. The
+// Local scope is only offered when the engine can read frames.
 func (da *DebugAdapter) getLocalVariables(frameID int) []Variable {
+	if !da.frames.canReadFrames() {
+		return []Variable{}
+	}
+
+	var variables []Variable
+
+	// Get frame from our map
+	frame, ok := da.frameMap[frameID]
+	if !ok {
+		log.Printf("Frame %d not found", frameID)
+		return variables
+	}
+
+	funcName := frame.FuncName()
+	log.Printf("Getting variables for function '%s'", funcName)
+
+	localVars := da.frames.localVariables(frame)
+	log.Printf("Found %d local variables", len(localVars))
+
+	args := da.frames.arguments(frame)
+	log.Printf("Found %d arguments", len(args))
+
+	// Show arguments under their declared parameter names
+	shown := make(map[string]bool)
+	params, ok := da.frameParameters(frameID)
+	if ok {
+		variables = append(variables, da.parameterVariables(frameIndex(frameID), params, args, localVars, shown)...)
+	} else {
+		for i, arg := range args {
+			variables = append(variables, da.valueToVariable(fmt.Sprintf("argument[%d]", i), arg))
+		}
+	}
+
+	for name, value := range localVars {
+		if shown[name] {
+			continue
+		}
+		variables = append(variables, da.valueToVariable(name, value))
+	}
+
+	// Arguments passed beyond the declared parameters
+	if ok && params.Rest == nil && len(args) > len(params.List) && localVars["arguments"] == nil && !shown["arguments"] {
+		items := make([]interface{}, len(args))
+		for i, arg := range args {
+			items[i] = arg
+		}
+		variables = append(variables, da.valueToVariable("arguments", da.vm.NewArray(items...)))
+	}
+
+	thisValue := da.frames.this(frame)
+	if thisValue != nil && !goja.IsUndefined(thisValue) {
+		variables = append(variables, da.valueToVariable("this", thisValue))
+	}
+
+	return variables
+}
+
+// parameterVariables pairs the arguments of a frame with the parameters its
+// function declares. Simple parameters take their argument, unless the
+// frame's locals hold a newer value or a default. Destructured parameters
+// are shown by the names they bind, read in the frame when they aren't among
+// its locals, and a rest parameter gets the remaining arguments. Names shown
+// are added to shown.
+func (da *DebugAdapter) parameterVariables(frameIndex int, params *ast.ParameterList, args []goja.Value, localVars map[string]goja.Value, shown map[string]bool) []Variable {
 	var variables []Variable
+	bound := func(name string) goja.Value {
+		if val, ok := localVars[name]; ok {
+			return val
+		}
+		if val, err := da.evaluateInFrame(name, frameIndex); err == nil {
+			return val
+		}
+		return goja.Undefined()
+	}
+	add := func(name string, val goja.Value) {
+		if shown[name] {
+			return
+		}
+		if local, ok := localVars[name]; ok {
+			val = local
+		}
+		shown[name] = true
+		variables = append(variables, da.valueToVariable(name, val))
+	}
+
+	for i, param := range params.List {
+		if id, ok := param.Target.(*ast.Identifier); ok {
+			var val goja.Value = goja.Undefined()
+			if i < len(args) {
+				val = args[i]
+			}
+			add(id.Name.String(), val)
+			continue
+		}
+		for _, name := range bindingNames(param.Target) {
+			add(name, bound(name))
+		}
+	}
 
-	// Get frame info
-	if frame, ok := da.frameMap[frameID]; ok {
-		funcName := frame.FuncName()
-		log.Printf("Frame %d function: '%s'", frameID, funcName)
-
-		// If we're in a function, show detected variables (without evaluating)
-		if funcName != "" && funcName != "(anonymous)" {
-			// Show detected variables for this function
-			if varNames, ok := da.functionScopes[funcName]; ok {
-				// Add a note about variables
-				variables = append(variables, Variable{
-					Name:               "[Info]",
-					Value:              fmt.Sprintf("Function '%s' has %d detected variables", funcName, len(varNames)),
-					Type:               "string",
-					VariablesReference: 0,
-				})
-
-				// List detected variables (without evaluating)
-				for _, varName := range varNames {
-					variables = append(variables, Variable{
-						Name:               varName,
-						Value:              "(use Debug Console to evaluate)",
-						Type:               "unknown",
-						VariablesReference: 0,
-					})
-				}
+	if params.Rest != nil {
+		if id, ok := params.Rest.(*ast.Identifier); ok {
+			var rest []interface{}
+			for i := len(params.List); i < len(args); i++ {
+				rest = append(rest, args[i])
+			}
+			add(id.Name.String(), da.vm.NewArray(rest...))
+		} else {
+			for _, name := range bindingNames(params.Rest) {
+				add(name, bound(name))
 			}
 		}
 	}
@@ -661,36 +664,7 @@ func (da *DebugAdapter) getGlobalVariables() []Variable {
 
 			val := globalObj.Get(key)
 			if val != nil {
-				value := "undefined"
-				varType := "undefined"
-				varRef := 0
-
-				if !goja.IsUndefined(val) && !goja.IsNull(val) {
-					value = val.String()
-					varType = da.getValueType(val)
-
-					// Create reference for complex types
-					if obj, ok := val.(*goja.Object); ok {
-						if _, isFunc := goja.AssertFunction(obj); !isFunc {
-							da.varRefCounter++
-							varRef = da.varRefCounter
-							da.varRefMap[varRef] = val
-							value = da.formatComplexValue(val)
-						} else {
-							value = fmt.Sprintf("[Function: %s]", key)
-						}
-					}
-				} else if goja.IsNull(val) {
-					value = "null"
-					varType = "null"
-				}
-
-				variables = append(variables, Variable{
-					Name:               key,
-					Value:              value,
-					Type:               varType,
-					VariablesReference: varRef,
-				})
+				variables = append(variables, da.valueToVariable(key, val))
 			}
 		}
 	}
@@ -698,6 +672,12 @@ func (da *DebugAdapter) getGlobalVariables() []Variable {
 	return variables
 }
 
+// valueToVariable renders a value for the Variables view. Strings are shown
+// without quotes.
+func (da *DebugAdapter) valueToVariable(name string, val goja.Value) Variable {
+	return da.describeValue(name, val)
+}
+
 func (da *DebugAdapter) getObjectProperties(val goja.Value) []Variable {
 	var variables []Variable
 
@@ -709,47 +689,7 @@ func (da *DebugAdapter) getObjectProperties(val goja.Value) []Variable {
 	for _, key := range obj.Keys() {
 		propVal := obj.Get(key)
 		if propVal != nil {
-			value := "undefined"
-			varType := "undefined"
-			varRef := 0
-
-			if !goja.IsUndefined(propVal) && !goja.IsNull(propVal) {
-				value = propVal.String()
-				varType = da.getValueType(propVal)
-
-				// Create reference for nested objects
-				if innerObj, ok := propVal.(*goja.Object); ok {
-					if _, isFunc := goja.AssertFunction(innerObj); !isFunc {
-						da.varRefCounter++
-						varRef = da.varRefCounter
-						da.varRefMap[varRef] = propVal
-						value = da.formatComplexValue(propVal)
-					}
-				}
-			} else if goja.IsNull(propVal) {
-				value = "null"
-				varType = "null"
-			}
-
-			variables = append(variables, Variable{
-				Name:               key,
-				Value:              value,
-				Type:               varType,
-				VariablesReference: varRef,
-			})
-		}
-	}
-
-	// For arrays, add length property
-	if arr := obj.Export(); arr != nil {
-		if reflect.TypeOf(arr).Kind() == reflect.Slice {
-			s := reflect.ValueOf(arr)
-			variables = append([]Variable{{
-				Name:               "length",
-				Value:              strconv.Itoa(s.Len()),
-				Type:               "number",
-				VariablesReference: 0,
-			}}, variables...)
+			variables = append(variables, da.valueToVariable(key, propVal))
 		}
 	}
 
@@ -764,6 +704,16 @@ func (da *DebugAdapter) getValueType(val goja.Value) string {
 		return "null"
 	}
 
+	if obj, ok := val.(*goja.Object); ok {
+		if _, ok := goja.AssertFunction(obj); ok {
+			return "function"
+		}
+		if isArray(obj) {
+			return "array"
+		}
+		return "object"
+	}
+
 	switch val.Export().(type) {
 	case string:
 		return "string"
@@ -772,16 +722,6 @@ func (da *DebugAdapter) getValueType(val goja.Value) string {
 	case bool:
 		return "boolean"
 	default:
-		if obj, ok := val.(*goja.Object); ok {
-			if _, ok := goja.AssertFunction(obj); ok {
-				return "function"
-			}
-			// Check if it's an array
-			if arr := obj.Export(); arr != nil && reflect.TypeOf(arr).Kind() == reflect.Slice {
-				return "array"
-			}
-			return "object"
-		}
 		return "unknown"
 	}
 }
@@ -793,9 +733,8 @@ func (da *DebugAdapter) formatComplexValue(val goja.Value) string {
 	}
 
 	// Check if it's an array
-	if arr := obj.Export(); arr != nil && reflect.TypeOf(arr).Kind() == reflect.Slice {
-		s := reflect.ValueOf(arr)
-		return fmt.Sprintf("Array[%d]", s.Len())
+	if isArray(obj) {
+		return fmt.Sprintf("Array[%d]", arrayLength(obj))
 	}
 
 	// For objects, show a preview
@@ -826,6 +765,71 @@ func (da *DebugAdapter) isBuiltIn(name string) bool {
 	return false
 }
 
+// evaluateGlobal evaluates an expression against the global scope while
+// execution is paused.
+func (da *DebugAdapter) evaluateGlobal(expression string) (goja.Value, error) {
+	// Disable the handler so the evaluation itself doesn't pause
+	defer da.swapDebugHandler(nil, false)()
+
+	return da.vm.RunString(expression)
+}
+
+// evaluateInFrame evaluates an expression in the scope of the given frame
+// (0 is the innermost one) while execution is paused. Engines that can't
+// reach into frame scopes evaluate it against the global scope, callers use
+// checkFrameEvaluation to refuse what the frame would see differently.
+func (da *DebugAdapter) evaluateInFrame(expression string, frameIndex int) (goja.Value, error) {
+	// Disable the handler so the evaluation itself doesn't pause
+	defer da.swapDebugHandler(nil, false)()
+
+	return da.frames.evaluateInFrame(da.vm, expression, frameIndex)
+}
+
+// setDebugHandler installs the handler the engine pauses in.
+func (da *DebugAdapter) setDebugHandler(handler func(*debugState) debugCommand) {
+	da.handler = handler
+	da.debugger.SetHandler(handler)
+}
+
+// setStepMode turns the engine's step mode on or off.
+func (da *DebugAdapter) setStepMode(enabled bool) {
+	da.stepMode = enabled
+	da.debugger.SetStepMode(enabled)
+}
+
+// swapDebugHandler installs a handler and step mode for code the adapter
+// runs while paused, and returns a func that puts the previous ones back. A
+// nil handler runs the code without pausing.
+func (da *DebugAdapter) swapDebugHandler(handler func(*debugState) debugCommand, stepMode bool) (restore func()) {
+	handlerBefore, stepModeBefore := da.handler, da.stepMode
+	da.setDebugHandler(handler)
+	da.setStepMode(stepMode)
+	return func() {
+		da.setDebugHandler(handlerBefore)
+		da.setStepMode(stepModeBefore)
+	}
+}
+
+// readScopeVariable reads a variable of a scope level by evaluating its name
+// in the given frame. Shadowed names are never read this way. Without frame
+// access only script scope variables are in reach, the other scopes aren't
+// offered.
+func (da *DebugAdapter) readScopeVariable(frameIndex int, scope *lexicalScope, name string) (goja.Value, error) {
+	if scope.kind != "script" && !da.frames.canReadFrames() {
+		return nil, fmt.Errorf("not available in this goja build")
+	}
+	return da.evaluateInFrame(name, frameIndex)
+}
+
+// setLocalVariable assigns a function local in the given frame by
+// evaluating the assignment in that frame's scope.
+func (da *DebugAdapter) setLocalVariable(frameIndex int, name, value string) (goja.Value, error) {
+	if !da.frames.canReadFrames() {
+		return nil, fmt.Errorf("cannot modify local '%s': this goja build can't access frame scopes", name)
+	}
+	return da.evaluateInFrame(fmt.Sprintf("%s = (%s)", name, value), frameIndex)
+}
+
 func (da *DebugAdapter) handleEvaluate(req *Request) {
 	var args EvaluateArguments
 	if req.Arguments != nil {
@@ -834,13 +838,34 @@ func (da *DebugAdapter) handleEvaluate(req *Request) {
 	}
 
 	// Temporarily disable debugger to avoid recursive calls
-	da.debugger.SetHandler(nil)
-
-	// Try to evaluate the expression
-	result, err := da.vm.RunString(args.Expression)
+	restore := da.swapDebugHandler(nil, false)
+
+	// Watch, hover and console input run in the selected frame. Engines that
+	// can't read frames run them against the global scope, once checked to
+	// mean the same there. Watches and hovers must not change the program's
+	// state. Console input is kept as a source of its own, functions it
+	// defines can be stepped into later.
+	var result goja.Value
+	var err error
+	if args.FrameID > 0 {
+		err = da.checkFrameEvaluation(args.Expression, args.FrameID)
+	}
+	switch {
+	case err != nil:
+	case args.Context == "hover" || args.Context == "watch":
+		log.Printf("Evaluating '%s' without side effects", args.Expression)
+		result, err = da.evaluateWithoutSideEffects(args.Expression, args.FrameID)
+	case args.FrameID > 0 && da.frames.canReadFrames():
+		log.Printf("Evaluating '%s' in frame %d", args.Expression, args.FrameID)
+		result, err = da.frames.evaluateInFrame(da.vm, args.Expression, frameIndex(args.FrameID))
+	case args.Context == "repl":
+		result, err = da.runConsoleScript(args.Expression)
+	default:
+		result, err = da.vm.RunString(args.Expression)
+	}
 
 	// Restore handler
-	da.debugger.SetHandler(da.debugHandler)
+	restore()
 
 	if err != nil {
 		da.sendResponse(req.Seq, req.Command, false, map[string]string{
@@ -851,18 +876,20 @@ func (da *DebugAdapter) handleEvaluate(req *Request) {
 
 	value := "(undefined)"
 	varRef := 0
+	indexed, named := 0, 0
 
 	if result != nil && !goja.IsUndefined(result) {
-		value = result.String()
-
-		// Create reference for complex types
-		if obj, ok := result.(*goja.Object); ok {
-			if _, isFunc := goja.AssertFunction(obj); !isFunc {
-				da.varRefCounter++
-				varRef = da.varRefCounter
-				da.varRefMap[varRef] = result
-				value = da.formatComplexValue(result)
-			}
+		// Create reference for complex types. Their String() is not used, it
+		// would join whole arrays or run a toString() from the script.
+		obj, ok := result.(*goja.Object)
+		if _, isFunc := goja.AssertFunction(result); ok && !isFunc {
+			da.varRefCounter++
+			varRef = da.varRefCounter
+			da.varRefMap[varRef] = result
+			value = da.formatComplexValue(result)
+			indexed, named = childCounts(obj)
+		} else {
+			value = result.String()
 		}
 	}
 
@@ -870,6 +897,8 @@ func (da *DebugAdapter) handleEvaluate(req *Request) {
 		Result:             value,
 		Type:               da.getValueType(result),
 		VariablesReference: varRef,
+		NamedVariables:     named,
+		IndexedVariables:   indexed,
 	})
 }
 
@@ -877,8 +906,8 @@ func (da *DebugAdapter) handleContinue(req *Request) {
 	log.Printf("=== CONTINUE: Setting next command to Continue")
 
 	da.debugStateMutex.Lock()
-	da.nextCommand = goja.DebugContinue
-	da.debugger.SetStepMode(false)
+	da.nextCommand = debugContinue
+	da.setStepMode(false)
 
 	if da.waitingForCmd {
 		da.waitingForCmd = false
@@ -896,8 +925,8 @@ func (da *DebugAdapter) handleNext(req *Request) {
 	log.Printf("=== NEXT: Setting next command to StepOver")
 
 	da.debugStateMutex.Lock()
-	da.nextCommand = goja.DebugStepOver
-	da.debugger.SetStepMode(true)
+	da.nextCommand = debugStepOver
+	da.setStepMode(true)
 
 	if da.waitingForCmd {
 		da.waitingForCmd = false
@@ -910,9 +939,16 @@ func (da *DebugAdapter) handleNext(req *Request) {
 }
 
 func (da *DebugAdapter) handleStepIn(req *Request) {
+	var args StepInArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
 	da.debugStateMutex.Lock()
-	da.nextCommand = goja.DebugStepInto
-	da.debugger.SetStepMode(true)
+	da.setStepInTarget(args.TargetID)
+	da.nextCommand = debugStepInto
+	da.setStepMode(true)
 
 	if da.waitingForCmd {
 		da.waitingForCmd = false
@@ -926,8 +962,8 @@ func (da *DebugAdapter) handleStepIn(req *Request) {
 
 func (da *DebugAdapter) handleStepOut(req *Request) {
 	da.debugStateMutex.Lock()
-	da.nextCommand = goja.DebugStepOut
-	da.debugger.SetStepMode(true)
+	da.nextCommand = debugStepOut
+	da.setStepMode(true)
 
 	if da.waitingForCmd {
 		da.waitingForCmd = false
@@ -940,7 +976,7 @@ func (da *DebugAdapter) handleStepOut(req *Request) {
 }
 
 func (da *DebugAdapter) handlePause(req *Request) {
-	da.debugger.SetStepMode(true)
+	da.setStepMode(true)
 	da.sendResponse(req.Seq, req.Command, true, nil)
 }
 
@@ -955,15 +991,46 @@ func (da *DebugAdapter) handleTerminate(req *Request) {
 	da.terminated = true
 }
 
-func (da *DebugAdapter) debugHandler(state *goja.DebuggerState) goja.DebugCommand {
+func (da *DebugAdapter) debugHandler(state *debugState) debugCommand {
+	// A restart is interrupting the script, don't stop again
+	if da.restarting {
+		return debugContinue
+	}
+
+	// Breakpoints are matched on every instruction. While running to one,
+	// the instructions that aren't at a breakpoint are skipped first.
+	stops := da.stopPointsAt(state.CallStack)
+	if len(stops) == 0 && da.dataHit == nil && da.stepTarget == nil {
+		da.debugStateMutex.Lock()
+		running := da.nextCommand == debugContinue
+		da.debugStateMutex.Unlock()
+		if running {
+			return da.continueCommand()
+		}
+	}
+
 	log.Printf("\n=== DEBUG HANDLER ===")
 	log.Printf("Position: %s:%d:%d", state.SourcePos.Filename, state.SourcePos.Line, state.SourcePos.Column)
-	log.Printf("Has Breakpoint: %v", state.Breakpoint != nil)
+	log.Printf("Stop points reached: %d", len(stops))
+
+	// The adapter's own reads while paused must not trip data breakpoints
+	da.inDebugHandler = true
+	defer func() { da.inDebugHandler = false }()
 
-	// Update current function context
-	if len(da.frameMap) > 0 {
-		if frame, ok := da.frameMap[1]; ok {
-			da.currentFunction = frame.FuncName()
+	// If a data breakpoint's accessor asked for this pause, stop here even
+	// if SourcePos looks like a position that would be skipped
+	dataReason := da.dataBreakpointStopReason()
+	if dataReason != "" {
+		da.stepTarget = nil
+	}
+
+	// A targeted stepIn keeps going until it enters the chosen call. This
+	// only looks at the call stack, SourcePos can be stale between statements.
+	if da.stepTarget != nil {
+		if len(stops) > 0 {
+			da.stepTarget = nil
+		} else if cmd, keepGoing := da.stepTargetCommand(state); keepGoing {
+			return cmd
 		}
 	}
 
@@ -980,7 +1047,7 @@ func (da *DebugAdapter) debugHandler(state *goja.DebuggerState) goja.DebugComman
 			da.debugStateMutex.Unlock()
 
 			// In step mode, only stop at the beginning of the line
-			if currentCmd != goja.DebugContinue && !strings.HasPrefix(strings.TrimSpace(line), "console.log") {
+			if dataReason == "" && len(stops) == 0 && currentCmd != debugContinue && !strings.HasPrefix(strings.TrimSpace(line), "console.log") {
 				log.Printf("Skipping console.log internal position")
 				return currentCmd
 			}
@@ -988,15 +1055,20 @@ func (da *DebugAdapter) debugHandler(state *goja.DebuggerState) goja.DebugComman
 	}
 
 	// Check if we're at the end of the script
-	if state.SourcePos.Filename == "" && state.SourcePos.Line == 0 {
+	if dataReason == "" && len(stops) == 0 && state.SourcePos.Filename == "" && state.SourcePos.Line == 0 {
 		log.Printf("End of script - continuing")
-		return goja.DebugContinue
+		return da.continueCommand()
 	}
 
 	// Determine stop reason
+	bpReason := dataReason
+	if bpReason == "" {
+		bpReason = da.breakpointStopReason(stops)
+	}
+	hitBreakpoint := bpReason != ""
 	reason := "step"
-	if state.Breakpoint != nil {
-		reason = "breakpoint"
+	if hitBreakpoint {
+		reason = bpReason
 		log.Printf("Hit breakpoint at line %d", state.SourcePos.Line)
 	}
 
@@ -1006,30 +1078,43 @@ func (da *DebugAdapter) debugHandler(state *goja.DebuggerState) goja.DebugComman
 	da.debugStateMutex.Unlock()
 
 	// If we're in continue mode and there's no breakpoint, just continue
-	if currentCmd == goja.DebugContinue && state.Breakpoint == nil {
+	if currentCmd == debugContinue && !hitBreakpoint {
 		log.Printf("In continue mode with no breakpoint - continuing")
-		return goja.DebugContinue
+		return da.continueCommand()
 	}
 
 	// Send stopped event
-	da.sendEvent("stopped", StoppedEventBody{
+	stopped := StoppedEventBody{
 		Reason:            reason,
 		ThreadID:          da.threadID,
 		AllThreadsStopped: true,
-	})
-
-	// Wait for command
+	}
+	if da.exception != nil {
+		stopped.Text = da.exception.description
+	}
+	// Be ready for the command before the client hears about the stop, it
+	// may answer right away
 	da.debugStateMutex.Lock()
 	da.waitingForCmd = true
+	commandReady := da.commandReady
 	da.debugStateMutex.Unlock()
 
+	da.sendEvent("stopped", stopped)
+
 	log.Printf("Waiting for debugger command...")
-	<-da.commandReady
+	<-commandReady
+	da.exception = nil
+	da.dataHit = nil
+	da.forgetPausedStack()
 
 	da.debugStateMutex.Lock()
 	cmd := da.nextCommand
 	da.debugStateMutex.Unlock()
 
+	if cmd == debugContinue {
+		cmd = da.continueCommand()
+	}
+
 	log.Printf("Returning command: %v", cmd)
 	return cmd
 }
@@ -1042,12 +1127,18 @@ func (da *DebugAdapter) startExecution() {
 
 	da.running = false
 
+	// A restart interrupted the script, the new run reports its own end
+	if da.restarting {
+		return
+	}
+
 	if err != nil {
 		log.Printf("Script error: %v", err)
 		da.sendEvent("output", map[string]interface{}{
 			"category": "stderr",
 			"output":   fmt.Sprintf("Error: %v\n", err),
 		})
+		da.reportUncaughtException(err)
 	}
 
 	// Send exit event
@@ -0,0 +1,855 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+)
+
+func (da *DebugAdapter) handleSetBreakpoints(req *Request) {
+	var args SetBreakpointsArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	// Clear existing breakpoints for this file
+	filename := da.sourceFilename(args.Source)
+
+	log.Printf("SetBreakpoints request for file: %s, breakpoints: %d", filename, len(args.Breakpoints))
+
+	positions := da.sourcePositions(args.Source)
+	da.scriptsMutex.Lock()
+	prg := da.scriptASTs[filename]
+	da.scriptsMutex.Unlock()
+
+	da.bpMutex.Lock()
+
+	// Forget the old breakpoints of this file
+	for _, bpID := range da.breakpoints[filename] {
+		delete(da.bpMap, bpID)
+		delete(da.bpOptions, bpID)
+		delete(da.bpHits, bpID)
+		delete(da.bpReported, bpID)
+	}
+	da.breakpoints[filename] = []int{}
+
+	// Add new breakpoints
+	breakpoints := []Breakpoint{}
+
+	for _, sbp := range args.Breakpoints {
+		da.bpIDCounter++
+		bpID := da.bpIDCounter
+
+		bp := Breakpoint{
+			ID:     bpID,
+			Source: args.Source,
+		}
+		resolveSourceBreakpoint(&bp, sbp, filename, prg, positions)
+
+		da.bpMap[bpID] = &bp
+		da.bpOptions[bpID] = sbp
+		da.breakpoints[filename] = append(da.breakpoints[filename], bpID)
+		breakpoints = append(breakpoints, bp)
+
+		log.Printf("Added breakpoint: file=%s, line=%d, column=%d, resolved to %d:%d, verified=%v, condition=%q, hitCondition=%q",
+			filename, sbp.Line, sbp.Column, bp.Line, bp.Column, bp.Verified, sbp.Condition, sbp.HitCondition)
+	}
+	da.rebuildStopTable()
+	da.bpMutex.Unlock()
+
+	da.sendResponse(req.Seq, req.Command, true, SetBreakpointsResponseBody{
+		Breakpoints: breakpoints,
+	})
+
+	da.watchStopPoints()
+}
+
+// watchStopPoints turns on step mode while the program runs if breakpoints
+// were added. They are matched by the debug handler, which only runs in step
+// mode.
+func (da *DebugAdapter) watchStopPoints() {
+	da.debugStateMutex.Lock()
+	if da.debugger != nil && da.nextCommand == debugContinue && da.hasSourceBreakpoints() {
+		da.setStepMode(true)
+	}
+	da.debugStateMutex.Unlock()
+}
+
+// resolveSourceBreakpoint places bp on the position goja compiled code for
+// that is nearest to the requested one, at or after it but within the
+// statement, block or function around it in prg, unless prg is nil. A
+// breakpoint that can't be placed is unverified and says why.
+func resolveSourceBreakpoint(bp *Breakpoint, sbp SourceBreakpoint, filename string, prg *ast.Program, positions []file.Position) {
+	bp.Verified = false
+	bp.Line = sbp.Line
+	bp.Column = sbp.Column
+	bp.Message = ""
+	bp.Reason = "failed"
+
+	if sbp.HitCondition != "" {
+		if _, err := matchHitCondition(sbp.HitCondition, 0); err != nil {
+			bp.Message = err.Error()
+			return
+		}
+	}
+
+	if !hasDebugger {
+		bp.Message = "This goja build has no debugger, breakpoints can't pause"
+		return
+	}
+	if positions == nil {
+		// Placed once the runtime loads the file
+		bp.Message = fmt.Sprintf("%s is not loaded yet", filepath.Base(filename))
+		bp.Reason = "pending"
+		return
+	}
+	pos, ok := resolvePosition(positions, sbp.Line, sbp.Column)
+	if !ok {
+		bp.Message = fmt.Sprintf("No code at or after line %d", sbp.Line)
+		return
+	}
+	if end, bounded := snapLimit(prg, sbp.Line, sbp.Column); bounded && !positionBefore(pos, end) {
+		// Code further down belongs to another statement
+		bp.Message = fmt.Sprintf("No code at line %d or after it in its block", sbp.Line)
+		return
+	}
+
+	bp.Verified = true
+	bp.Reason = ""
+	bp.Line = pos.Line
+	bp.Column = pos.Column
+}
+
+// reresolveBreakpoints places the breakpoints of a file again after its code
+// was loaded or changed, and tells the client about the ones that moved.
+func (da *DebugAdapter) reresolveBreakpoints(filename string) {
+	da.bpMutex.Lock()
+	ids := da.breakpoints[filename]
+	var source Source
+	if len(ids) > 0 {
+		source = da.bpMap[ids[0]].Source
+	}
+	da.bpMutex.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	// All breakpoints of a file were set with the same source
+	positions := da.sourcePositions(source)
+	da.scriptsMutex.Lock()
+	prg := da.scriptASTs[filename]
+	da.scriptsMutex.Unlock()
+
+	var changed []Breakpoint
+	da.bpMutex.Lock()
+	for _, bpID := range ids {
+		bp, ok := da.bpMap[bpID]
+		if !ok {
+			continue
+		}
+		before := *bp
+		resolveSourceBreakpoint(bp, da.bpOptions[bpID], filename, prg, positions)
+		if *bp != before {
+			changed = append(changed, *bp)
+		}
+	}
+	da.rebuildStopTable()
+	da.bpMutex.Unlock()
+
+	for _, bp := range changed {
+		log.Printf("Breakpoint %d moved to %s:%d:%d, verified=%v", bp.ID, filename, bp.Line, bp.Column, bp.Verified)
+		da.sendEvent("breakpoint", BreakpointEventBody{
+			Reason:     "changed",
+			Breakpoint: bp,
+		})
+	}
+}
+
+// executionPoint is a position execution went through at some call depth.
+type executionPoint struct {
+	filename     string // or the script's key, for eval code
+	line, column int
+}
+
+// stopPoint is a place execution can stop at for a breakpoint.
+type stopPoint struct {
+	column int        // 0 stops when a frame enters the line
+	entry  bool       // function entry: only stops when the frame gets there from before it
+	bpID   int        // source or function breakpoint
+	throw  *throwSite // or throw statement, for exception breakpoints
+}
+
+// stopTable is a snapshot of where execution can stop, rebuilt whenever
+// breakpoints change. The debug handler reads it on every instruction
+// without taking bpMutex.
+type stopTable struct {
+	active  bool                           // anything can stop, now or once its file is loaded
+	pending map[string]bool                // files with breakpoints waiting for them to load
+	lines   map[string]map[int][]stopPoint // filename -> line -> stop points
+}
+
+// rebuildStopTable takes a new snapshot of the breakpoints. The caller must
+// hold bpMutex.
+func (da *DebugAdapter) rebuildStopTable() {
+	table := &stopTable{
+		pending: make(map[string]bool),
+		lines:   make(map[string]map[int][]stopPoint),
+	}
+	for filename, ids := range da.breakpoints {
+		for _, bpID := range ids {
+			bp := da.bpMap[bpID]
+			switch {
+			case bp == nil:
+			case bp.Verified:
+				column := 0
+				if da.bpOptions[bpID].Column > 0 {
+					column = bp.Column
+				}
+				table.add(filename, bp.Line, stopPoint{column: column, bpID: bpID})
+			case bp.Reason == "pending":
+				table.pending[filename] = true
+				table.active = true
+			}
+		}
+	}
+	for bpID, locations := range da.functionStops {
+		if bp := da.bpMap[bpID]; bp == nil || !bp.Verified {
+			continue
+		}
+		for _, pos := range locations {
+			table.add(pos.Filename, pos.Line, stopPoint{column: pos.Column, entry: true, bpID: bpID})
+		}
+	}
+	for _, site := range da.throwSites {
+		table.add(site.pos.Filename, site.pos.Line, stopPoint{column: site.pos.Column, throw: site})
+	}
+	da.stops.Store(table)
+}
+
+func (t *stopTable) add(filename string, line int, point stopPoint) {
+	if t.lines[filename] == nil {
+		t.lines[filename] = make(map[int][]stopPoint)
+	}
+	t.lines[filename][line] = append(t.lines[filename][line], point)
+	t.active = true
+}
+
+// stopTable returns the current snapshot of the breakpoints.
+func (da *DebugAdapter) stopTable() *stopTable {
+	if table := da.stops.Load(); table != nil {
+		return table
+	}
+	return &stopTable{}
+}
+
+// stopPointsAt returns the breakpoints and throw statements execution just
+// reached. goja's own breakpoints stop at the wrong instruction (see
+// BREAKPOINT_MAPPING_ISSUE.md), so the handler looks at every instruction
+// instead. A line breakpoint is reached when a frame enters its line, the
+// others when a frame enters their exact position. This runs for every
+// instruction, so it only does map lookups on the breakpoint snapshot, and
+// only when the position changed.
+func (da *DebugAdapter) stopPointsAt(stack []goja.StackFrame) []stopPoint {
+	if len(stack) == 0 {
+		return nil
+	}
+	pos := stack[0].Position()
+	filename := pos.Filename
+	if filename == evalSourceName {
+		// Breakpoints in eval code are stored under the script's key
+		if script := da.evalScript(&stack[0]); script != nil {
+			filename = script.key()
+		}
+	}
+	point := executionPoint{filename, pos.Line, pos.Column}
+
+	// Remember the last point of every frame, deeper frames have returned
+	depth := len(stack)
+	var last executionPoint
+	if depth <= len(da.lastPoints) {
+		last = da.lastPoints[depth-1]
+		da.lastPoints = da.lastPoints[:depth]
+	} else {
+		for len(da.lastPoints) < depth {
+			da.lastPoints = append(da.lastPoints, executionPoint{})
+		}
+	}
+	da.lastPoints[depth-1] = point
+
+	if point == last {
+		return nil
+	}
+
+	table := da.stopTable()
+
+	// Host code can run scripts the adapter hasn't seen, place their
+	// breakpoints on the first instruction
+	if point.filename != last.filename && table.pending[pos.Filename] && da.scriptByName(pos.Filename) == nil {
+		da.discoverScript(pos.Filename)
+		table = da.stopTable()
+	}
+
+	newLine := last.filename != point.filename || last.line != point.line
+	var reached []stopPoint
+	for _, stop := range table.lines[filename][pos.Line] {
+		switch {
+		case stop.entry:
+			// The first statement's operands can run before its own
+			// position, so the stop is reached by the first point at or
+			// after it. Loops at the start of a function come back there,
+			// only a call gets there from before it.
+			if pos.Column >= stop.column && (last.filename != point.filename || last.line < point.line ||
+				(last.line == point.line && last.column < stop.column)) {
+				reached = append(reached, stop)
+			}
+		case stop.column > 0:
+			if stop.column == pos.Column {
+				reached = append(reached, stop)
+			}
+		case newLine:
+			reached = append(reached, stop)
+		}
+	}
+	return reached
+}
+
+// hasSourceBreakpoints reports whether any source or function breakpoint can
+// stop, now or once its file is loaded.
+func (da *DebugAdapter) hasSourceBreakpoints() bool {
+	return da.stopTable().active
+}
+
+// applyBreakpoints installs the stored breakpoints in a new runtime. Clients
+// may set breakpoints before launch, and a restart keeps them.
+func (da *DebugAdapter) applyBreakpoints() {
+	da.bpMutex.Lock()
+	var files []string
+	for filename, ids := range da.breakpoints {
+		// Breakpoints set before launch may name the program differently
+		if filename != da.program && filepath.Base(filename) == filepath.Base(da.program) {
+			da.breakpoints[da.program] = append(da.breakpoints[da.program], ids...)
+			delete(da.breakpoints, filename)
+			filename = da.program
+		}
+		files = append(files, filename)
+		for _, bpID := range ids {
+			delete(da.bpHits, bpID)
+			delete(da.bpReported, bpID)
+		}
+	}
+	da.rebuildStopTable()
+	da.bpMutex.Unlock()
+
+	for _, filename := range files {
+		da.reresolveBreakpoints(filename)
+	}
+	da.placeFunctionBreakpoints()
+	da.placeThrowBreakpoints()
+
+	if da.hasSourceBreakpoints() {
+		da.setStepMode(true)
+	}
+}
+
+// continueCommand is what the debug handler returns to let execution run.
+// With source breakpoints set it must keep seeing every instruction.
+func (da *DebugAdapter) continueCommand() debugCommand {
+	if da.hasSourceBreakpoints() {
+		return debugStepInto
+	}
+	return debugContinue
+}
+
+// breakpointStopReason decides whether execution should stop at the points
+// it reached, based on the options their breakpoints were set with and the
+// exception filters. It returns the DAP stop reason, or "" to keep going.
+func (da *DebugAdapter) breakpointStopReason(stops []stopPoint) string {
+	for _, stop := range stops {
+		reason := ""
+		if stop.throw != nil {
+			reason = da.throwStopReason(stop.throw)
+		} else {
+			reason = da.sourceBreakpointStopReason(stop.bpID)
+		}
+		if reason != "" {
+			return reason
+		}
+	}
+
+	// Conditions may have looked at the stack, it is captured again at the
+	// next pause
+	da.forgetPausedStack()
+	return ""
+}
+
+// sourceBreakpointStopReason checks the options of a source or function
+// breakpoint execution reached.
+func (da *DebugAdapter) sourceBreakpointStopReason(bpID int) string {
+	da.bpMutex.Lock()
+	opts := da.bpOptions[bpID]
+	_, isFunc := da.functionBPs[bpID]
+	da.bpMutex.Unlock()
+
+	if opts.Condition != "" && !da.conditionHolds(bpID, opts.Condition) {
+		return ""
+	}
+
+	// Only hits that passed the condition are counted
+	da.bpMutex.Lock()
+	da.bpHits[bpID]++
+	hits := da.bpHits[bpID]
+	da.bpMutex.Unlock()
+
+	if opts.HitCondition != "" {
+		matched, err := matchHitCondition(opts.HitCondition, hits)
+		if err != nil || !matched {
+			log.Printf("Breakpoint %d hit %d times, hit condition %q not met", bpID, hits, opts.HitCondition)
+			return ""
+		}
+		da.reportHits(bpID, hits)
+	}
+
+	// Logpoints print their message and never stop
+	if opts.LogMessage != "" {
+		da.bpMutex.Lock()
+		var bp Breakpoint
+		if b, ok := da.bpMap[bpID]; ok {
+			bp = *b
+		}
+		da.bpMutex.Unlock()
+
+		da.sendEvent("output", map[string]interface{}{
+			"category": "console",
+			"output":   da.interpolateLogMessage(bpID, opts.LogMessage) + "\n",
+			"source":   bp.Source,
+			"line":     bp.Line,
+			"column":   bp.Column,
+		})
+		return ""
+	}
+
+	if isFunc {
+		return "function breakpoint"
+	}
+	return "breakpoint"
+}
+
+// conditionHolds evaluates a breakpoint condition in the paused frame. A
+// condition that can't be evaluated there, or fails to, is reported once and
+// stops, so the breakpoint isn't silently skipped.
+func (da *DebugAdapter) conditionHolds(bpID int, condition string) bool {
+	result, err := da.evaluateBreakpointExpression(condition)
+	if err != nil {
+		log.Printf("Breakpoint %d condition %q failed: %v", bpID, condition, err)
+		da.reportBreakpointProblem(bpID, fmt.Sprintf("Condition '%s' can't be evaluated, stopping instead: %v", condition, err))
+		return true
+	}
+	if result == nil || !result.ToBoolean() {
+		log.Printf("Breakpoint %d condition %q is false - not stopping", bpID, condition)
+		return false
+	}
+	return true
+}
+
+// evaluateBreakpointExpression evaluates a condition or logpoint expression
+// in the paused frame. Expressions the engine would evaluate against other
+// values than the frame's are refused.
+func (da *DebugAdapter) evaluateBreakpointExpression(expr string) (goja.Value, error) {
+	if err := da.checkFrameEvaluation(expr, 1); err != nil {
+		return nil, err
+	}
+	return da.evaluateInFrame(expr, 0)
+}
+
+// reportBreakpointProblem shows why a breakpoint doesn't work as set, on the
+// breakpoint and in the output. It is only reported the first time, the
+// breakpoint may be hit over and over.
+func (da *DebugAdapter) reportBreakpointProblem(bpID int, message string) {
+	da.bpMutex.Lock()
+	if da.bpReported[bpID] {
+		da.bpMutex.Unlock()
+		return
+	}
+	da.bpReported[bpID] = true
+	var bp Breakpoint
+	if b, ok := da.bpMap[bpID]; ok {
+		b.Message = message
+		bp = *b
+	}
+	da.bpMutex.Unlock()
+
+	da.sendEvent("breakpoint", BreakpointEventBody{
+		Reason:     "changed",
+		Breakpoint: bp,
+	})
+	da.sendEvent("output", map[string]interface{}{
+		"category": "stderr",
+		"output":   fmt.Sprintf("Breakpoint %d: %s\n", bpID, message),
+	})
+}
+
+// reportHits shows on a breakpoint with a hit condition how often it has been
+// hit, each time the condition lets it stop or log. A problem reported on
+// the breakpoint stays shown instead.
+func (da *DebugAdapter) reportHits(bpID, hits int) {
+	da.bpMutex.Lock()
+	b, ok := da.bpMap[bpID]
+	if !ok || da.bpReported[bpID] {
+		da.bpMutex.Unlock()
+		return
+	}
+	if hits == 1 {
+		b.Message = "hit 1 time"
+	} else {
+		b.Message = fmt.Sprintf("hit %d times", hits)
+	}
+	bp := *b
+	da.bpMutex.Unlock()
+
+	da.sendEvent("breakpoint", BreakpointEventBody{
+		Reason:     "changed",
+		Breakpoint: bp,
+	})
+}
+
+// interpolateLogMessage replaces every {expression} in a logpoint message
+// with its value in the paused frame. Use {{ and }} for literal braces.
+// Expressions that can't be evaluated in the frame print <unavailable>, and
+// the logpoint reports why once.
+func (da *DebugAdapter) interpolateLogMessage(bpID int, message string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c == '}' && i+1 < len(message) && message[i+1] == '}' {
+			sb.WriteByte('}')
+			i++
+			continue
+		}
+		if c != '{' {
+			sb.WriteByte(c)
+			continue
+		}
+		if i+1 < len(message) && message[i+1] == '{' {
+			sb.WriteByte('{')
+			i++
+			continue
+		}
+
+		// Find the matching closing brace, allowing nested object literals
+		depth := 1
+		end := i + 1
+		for ; end < len(message) && depth > 0; end++ {
+			switch message[end] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+		}
+		if depth > 0 {
+			// Unbalanced, print the rest verbatim
+			sb.WriteString(message[i:])
+			break
+		}
+
+		expr := message[i+1 : end-1]
+		if err := da.checkFrameEvaluation(expr, 1); err != nil {
+			da.reportBreakpointProblem(bpID, fmt.Sprintf("'{%s}' can't be logged: %v", expr, err))
+			sb.WriteString("<unavailable>")
+		} else if val, err := da.evaluateInFrame(expr, 0); err != nil {
+			sb.WriteString(fmt.Sprintf("<error: %v>", err))
+		} else {
+			sb.WriteString(da.formatLogValue(val))
+		}
+		i = end - 1
+	}
+
+	return sb.String()
+}
+
+// formatLogValue renders a value the way console.log would show it.
+func (da *DebugAdapter) formatLogValue(val goja.Value) string {
+	if val == nil || goja.IsUndefined(val) {
+		return "undefined"
+	}
+	if goja.IsNull(val) {
+		return "null"
+	}
+	if obj, ok := val.(*goja.Object); ok {
+		if _, isFunc := goja.AssertFunction(obj); !isFunc {
+			return da.formatComplexValue(val)
+		}
+	}
+	return val.String()
+}
+
+func (da *DebugAdapter) handleSetFunctionBreakpoints(req *Request) {
+	var args SetFunctionBreakpointsArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	log.Printf("SetFunctionBreakpoints request, breakpoints: %d", len(args.Breakpoints))
+
+	da.bpMutex.Lock()
+
+	// Every request replaces the full set of function breakpoints
+	for bpID := range da.functionBPs {
+		delete(da.bpMap, bpID)
+		delete(da.bpOptions, bpID)
+		delete(da.bpHits, bpID)
+		delete(da.bpReported, bpID)
+		delete(da.functionBPs, bpID)
+		delete(da.functionStops, bpID)
+	}
+
+	var ids []int
+	for _, fbp := range args.Breakpoints {
+		da.bpIDCounter++
+		bpID := da.bpIDCounter
+
+		da.bpMap[bpID] = &Breakpoint{ID: bpID}
+		da.bpOptions[bpID] = SourceBreakpoint{
+			Condition:    fbp.Condition,
+			HitCondition: fbp.HitCondition,
+		}
+		da.functionBPs[bpID] = fbp
+		ids = append(ids, bpID)
+	}
+	da.bpMutex.Unlock()
+
+	da.placeFunctionBreakpoints()
+
+	da.bpMutex.Lock()
+	breakpoints := []Breakpoint{}
+	for _, bpID := range ids {
+		breakpoints = append(breakpoints, *da.bpMap[bpID])
+	}
+	da.bpMutex.Unlock()
+
+	da.sendResponse(req.Seq, req.Command, true, SetBreakpointsResponseBody{
+		Breakpoints: breakpoints,
+	})
+
+	da.watchStopPoints()
+}
+
+// placeFunctionBreakpoints resolves every function breakpoint against the
+// loaded scripts. Like source breakpoints they are matched by the debug
+// handler, at the first position compiled for each matching function.
+// Breakpoints that changed since they were last placed are reported to the
+// client.
+func (da *DebugAdapter) placeFunctionBreakpoints() {
+	da.bpMutex.Lock()
+	names := make(map[int]string)
+	for bpID, fbp := range da.functionBPs {
+		names[bpID] = fbp.Name
+	}
+	da.bpMutex.Unlock()
+
+	// Resolving compiles scripts, don't hold bpMutex meanwhile
+	found := make(map[int]int)
+	locations := make(map[int][]file.Position)
+	for bpID, name := range names {
+		found[bpID], locations[bpID] = da.findFunctionLocations(name)
+	}
+
+	da.bpMutex.Lock()
+	var changed []Breakpoint
+	for bpID, fbp := range da.functionBPs {
+		stops, ok := locations[bpID]
+		if !ok {
+			// Set while resolving, placed by the next call
+			continue
+		}
+
+		bp := da.bpMap[bpID]
+		before := *bp
+		*bp = Breakpoint{ID: bpID}
+
+		switch {
+		case found[bpID] == 0:
+			bp.Message = fmt.Sprintf("Function '%s' not found in loaded scripts", fbp.Name)
+			bp.Reason = "pending"
+		case len(stops) == 0:
+			bp.Message = fmt.Sprintf("Function '%s' has no code to stop at", fbp.Name)
+			bp.Reason = "failed"
+		default:
+			// A name can match several functions, all of them share one
+			// breakpoint, shown at the first
+			bp.Verified = true
+			bp.Source = Source{
+				Name: filepath.Base(stops[0].Filename),
+				Path: stops[0].Filename,
+			}
+			bp.Line = stops[0].Line
+			bp.Column = stops[0].Column
+			for _, pos := range stops {
+				log.Printf("Placed function breakpoint: name=%s, file=%s, line=%d, column=%d",
+					fbp.Name, pos.Filename, pos.Line, pos.Column)
+			}
+		}
+		da.functionStops[bpID] = stops
+
+		if !hasDebugger {
+			bp.Verified = false
+			bp.Message = "This goja build has no debugger, breakpoints can't pause"
+			bp.Reason = "failed"
+		}
+		if fbp.HitCondition != "" {
+			if _, err := matchHitCondition(fbp.HitCondition, 0); err != nil {
+				bp.Verified = false
+				bp.Message = err.Error()
+				bp.Reason = "failed"
+			}
+		}
+
+		opts := da.bpOptions[bpID]
+		opts.Line, opts.Column = bp.Line, bp.Column
+		da.bpOptions[bpID] = opts
+
+		if *bp != before && before != (Breakpoint{ID: bpID}) {
+			changed = append(changed, *bp)
+		}
+	}
+	da.rebuildStopTable()
+	da.bpMutex.Unlock()
+
+	for _, bp := range changed {
+		da.sendEvent("breakpoint", BreakpointEventBody{
+			Reason:     "changed",
+			Breakpoint: bp,
+		})
+	}
+}
+
+// findFunctionLocations resolves a function name such as "foo", "obj.method"
+// or "Class.prototype.method" to the functions it names in the loaded
+// scripts, and returns how many there are and where each one can be stopped
+// at: the first position compiled for its body, or for its parameters when
+// the body is empty. Nested functions don't count, they run in frames of
+// their own.
+func (da *DebugAdapter) findFunctionLocations(name string) (found int, locations []file.Position) {
+	da.scriptsMutex.Lock()
+	var matches []*jsFunction
+	var programs []*ast.Program
+	for _, prg := range da.scriptASTs {
+		for _, fn := range findFunctions(prg) {
+			if fn.matches(name) {
+				matches = append(matches, fn)
+				programs = append(programs, prg)
+			}
+		}
+	}
+	da.scriptsMutex.Unlock()
+
+	for i, fn := range matches {
+		prg := programs[i]
+		positions := da.sourcePositions(Source{Path: prg.File.Name()})
+		if pos, ok := functionEntry(prg, fn, positions); ok {
+			locations = append(locations, pos)
+		}
+	}
+	return len(matches), locations
+}
+
+// functionEntry picks the compiled position a function breakpoint stops at.
+func functionEntry(prg *ast.Program, fn *jsFunction, positions []file.Position) (file.Position, bool) {
+	start := nodePosition(prg, fn.Node.Idx0())
+	end := nodePosition(prg, fn.Node.Idx1())
+	body := nodePosition(prg, fn.Start)
+
+	var nested [][2]file.Position
+	walkAST(fn.Node, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.FunctionLiteral, *ast.ArrowFunctionLiteral, *ast.ClassLiteral:
+			if node != fn.Node {
+				nested = append(nested, [2]file.Position{nodePosition(prg, node.Idx0()), nodePosition(prg, node.Idx1())})
+				return false
+			}
+		}
+		return true
+	})
+
+	var first *file.Position
+	for i, pos := range positions {
+		if positionBefore(pos, start) || !positionBefore(pos, end) {
+			continue
+		}
+		inside := false
+		for _, r := range nested {
+			if !positionBefore(pos, r[0]) && positionBefore(pos, r[1]) {
+				inside = true
+				break
+			}
+		}
+		if inside {
+			continue
+		}
+		if !positionBefore(pos, body) {
+			return pos, true
+		}
+		if first == nil {
+			first = &positions[i]
+		}
+	}
+	if first != nil {
+		return *first, true
+	}
+	return file.Position{}, false
+}
+
+// positionBefore reports whether a comes before b in the same file.
+func positionBefore(a, b file.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// hitConditionPattern accepts the hit conditions VS Code users usually type:
+// "5" (same as ">= 5"), "== 3", "> 10", "% 5" and "% 5 == 2".
+var hitConditionPattern = regexp.MustCompile(`^\s*(>=|<=|===|==|>|<|%)?\s*(\d+)\s*(?:===?\s*(\d+)\s*)?$`)
+
+// matchHitCondition reports whether a breakpoint that has been hit hits
+// times satisfies the hit condition expr.
+func matchHitCondition(expr string, hits int) (bool, error) {
+	m := hitConditionPattern.FindStringSubmatch(expr)
+	if m == nil {
+		return false, fmt.Errorf("invalid hit condition '%s'", expr)
+	}
+
+	op := m[1]
+	n, _ := strconv.Atoi(m[2])
+	if m[3] != "" && op != "%" {
+		return false, fmt.Errorf("invalid hit condition '%s'", expr)
+	}
+
+	switch op {
+	case "", ">=":
+		return hits >= n, nil
+	case "<=":
+		return hits <= n, nil
+	case "==", "===":
+		return hits == n, nil
+	case ">":
+		return hits > n, nil
+	case "<":
+		return hits < n, nil
+	case "%":
+		if n == 0 {
+			return false, fmt.Errorf("invalid hit condition '%s': modulo by zero", expr)
+		}
+		rem, _ := strconv.Atoi(m[3])
+		return hits%n == rem, nil
+	}
+	return false, fmt.Errorf("invalid hit condition '%s'", expr)
+}
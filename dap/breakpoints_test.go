@@ -0,0 +1,202 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBreakpointSnapsToNextStatement(t *testing.T) {
+	s := newTestSession(t, `var total = 0;
+
+// add one
+total += 1;
+console.log(total);
+`)
+
+	bps := s.setBreakpoints(SourceBreakpoint{Line: 2}, SourceBreakpoint{Line: 7})
+	if !bps[0].Verified || bps[0].Line != 4 {
+		t.Errorf("breakpoint on a blank line = line %d, verified %v; want line 4, verified", bps[0].Line, bps[0].Verified)
+	}
+	if bps[1].Verified || bps[1].Message == "" {
+		t.Errorf("breakpoint after the last line = %+v; want unverified with a message", bps[1])
+	}
+	s.request("configurationDone", nil, nil)
+
+	stop, frame := s.stopped()
+	if stop.Reason != "breakpoint" || frame.Line != 4 {
+		t.Errorf("stopped for %q at line %d; want breakpoint at line 4", stop.Reason, frame.Line)
+	}
+	s.resume()
+	s.terminated()
+}
+
+func TestBreakpointStaysInItsBlock(t *testing.T) {
+	s := newTestSession(t, `function r(n) {
+  if (n == 0) {
+    return 0;
+  }
+
+  return r(n - 1);
+}
+r(2);
+`)
+
+	// goja compiles no code for return 0 that a breakpoint could stop at,
+	// the next code after it is outside its block. A blank line in the
+	// function still snaps to the function's next statement.
+	bps := s.setBreakpoints(SourceBreakpoint{Line: 3}, SourceBreakpoint{Line: 5})
+	if bps[0].Verified || bps[0].Message == "" {
+		t.Errorf("breakpoint on return 0 = line %d, verified %v; want unverified with a message", bps[0].Line, bps[0].Verified)
+	}
+	if !bps[1].Verified || bps[1].Line != 6 {
+		t.Errorf("breakpoint on the blank line = line %d, verified %v; want line 6, verified", bps[1].Line, bps[1].Verified)
+	}
+	s.request("configurationDone", nil, nil)
+
+	for call := 0; call < 2; call++ {
+		if _, frame := s.stopped(); frame.Line != 6 {
+			t.Errorf("stopped at line %d, want 6", frame.Line)
+		}
+		s.resume()
+	}
+	s.terminated()
+}
+
+func TestConditionalBreakpoint(t *testing.T) {
+	s := newTestSession(t, `var count = 0;
+for (var i = 0; i < 10; i++) {
+  count++;
+}
+`)
+
+	s.setBreakpoints(SourceBreakpoint{Line: 3, Condition: "count == 4"})
+	s.request("configurationDone", nil, nil)
+
+	_, frame := s.stopped()
+	if frame.Line != 3 {
+		t.Errorf("stopped at line %d, want 3", frame.Line)
+	}
+	if got := s.evaluate("count"); got != "4" {
+		t.Errorf("count = %s when the condition held, want 4", got)
+	}
+	s.resume()
+	s.terminated()
+}
+
+func TestHitConditionBreakpoint(t *testing.T) {
+	s := newTestSession(t, `var count = 0;
+for (var i = 0; i < 10; i++) {
+  count++;
+}
+`)
+
+	s.setBreakpoints(SourceBreakpoint{Line: 3, HitCondition: "% 4"})
+	s.request("configurationDone", nil, nil)
+
+	for _, want := range []struct{ count, message string }{{"3", "hit 4 times"}, {"7", "hit 8 times"}} {
+		s.stopped()
+		if got := s.evaluate("count"); got != want.count {
+			t.Errorf("count = %s at a hit, want %s", got, want.count)
+		}
+		var changed BreakpointEventBody
+		if err := json.Unmarshal(s.event("breakpoint").Body, &changed); err != nil {
+			t.Fatal(err)
+		}
+		if changed.Reason != "changed" || changed.Breakpoint.Message != want.message {
+			t.Errorf("breakpoint event %q with message %q, want changed with %q", changed.Reason, changed.Breakpoint.Message, want.message)
+		}
+		s.resume()
+	}
+	s.terminated()
+}
+
+func TestConditionOnLocalIsReportedOnce(t *testing.T) {
+	skipWithFrameAccess(t)
+
+	s := newTestSession(t, `function work(n) {
+  for (var i = 0; i < n; i++) {
+    n = n + 0;
+  }
+}
+work(3);
+`)
+
+	s.setBreakpoints(SourceBreakpoint{Line: 3, Condition: "i == 1"})
+	s.request("configurationDone", nil, nil)
+
+	// The condition can't be evaluated in the frame, so every hit stops
+	for hit := 0; hit < 3; hit++ {
+		s.stopped()
+		s.resume()
+	}
+	s.terminated()
+
+	var changed BreakpointEventBody
+	if err := json.Unmarshal(s.event("breakpoint").Body, &changed); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(changed.Breakpoint.Message, "i == 1") {
+		t.Errorf("breakpoint message = %q, want it to name the condition", changed.Breakpoint.Message)
+	}
+	if n := strings.Count(s.output("stderr"), "Breakpoint "); n != 1 {
+		t.Errorf("the condition was reported %d times, want once:\n%s", n, s.output("stderr"))
+	}
+}
+
+func TestFunctionBreakpoint(t *testing.T) {
+	s := newTestSession(t, `function add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+var x = add(1, 2);
+x = add(x, 3);
+`)
+
+	var body SetBreakpointsResponseBody
+	s.request("setFunctionBreakpoints", SetFunctionBreakpointsArguments{
+		Breakpoints: []FunctionBreakpoint{{Name: "add"}, {Name: "missing"}},
+	}, &body)
+	if bp := body.Breakpoints[0]; !bp.Verified || bp.Line != 2 {
+		t.Errorf("add breakpoint = line %d, verified %v; want line 2, verified", bp.Line, bp.Verified)
+	}
+	if bp := body.Breakpoints[1]; bp.Verified || bp.Message == "" {
+		t.Errorf("missing function breakpoint = %+v; want unverified with a message", bp)
+	}
+	s.request("configurationDone", nil, nil)
+
+	// Once per call
+	for call := 0; call < 2; call++ {
+		stop, frame := s.stopped()
+		if stop.Reason != "function breakpoint" || frame.Name != "add" || frame.Line != 2 {
+			t.Errorf("call %d stopped for %q in %s at line %d; want function breakpoint in add at line 2",
+				call+1, stop.Reason, frame.Name, frame.Line)
+		}
+		s.resume()
+	}
+	s.terminated()
+}
+
+func TestFunctionBreakpointAtLoop(t *testing.T) {
+	s := newTestSession(t, `function spin(n) {
+  while (n > 0) n--;
+  return n;
+}
+spin(5);
+spin(2);
+`)
+
+	s.request("setFunctionBreakpoints", SetFunctionBreakpointsArguments{
+		Breakpoints: []FunctionBreakpoint{{Name: "spin"}},
+	}, nil)
+	s.request("configurationDone", nil, nil)
+
+	// The loop comes back to the first statement, only calls stop
+	for call := 0; call < 2; call++ {
+		s.stopped()
+		s.resume()
+	}
+	s.terminated()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+)
+
+func (da *DebugAdapter) handleCompletions(req *Request) {
+	var args CompletionsArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	text := completionText(args.Text, args.Line, args.Column)
+	prefix := trailingIdentifier(text)
+	before := strings.TrimRight(text[:len(text)-len(prefix)], " \t")
+
+	var targets []CompletionItem
+	if da.vm != nil && (!da.running || da.isPaused()) {
+		if strings.HasSuffix(before, ".") && !strings.HasSuffix(before, "..") {
+			targets = da.memberCompletions(receiverExpression(before[:len(before)-1]), args.FrameID)
+		} else {
+			targets = da.scopeCompletions(args.FrameID)
+		}
+	}
+
+	items := []CompletionItem{}
+	for _, item := range targets {
+		if strings.HasPrefix(item.Label, prefix) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+
+	log.Printf("Completions for %q: %d items", text, len(items))
+
+	da.sendResponse(req.Seq, req.Command, true, CompletionsResponseBody{
+		Targets: items,
+	})
+}
+
+// scopeCompletions lists the identifiers visible in a frame: names declared
+// in the scopes enclosing it, innermost first, then the global object's
+// properties.
+func (da *DebugAdapter) scopeCompletions(frameID int) []CompletionItem {
+	var items []CompletionItem
+	seen := make(map[string]bool)
+
+	for _, level := range da.frameScopeChain(frameID) {
+		for _, name := range level.names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			items = append(items, CompletionItem{Label: name, Type: "variable"})
+		}
+	}
+
+	return append(items, da.propertyCompletions(da.vm.GlobalObject(), seen, "variable")...)
+}
+
+// memberCompletions lists the properties of the receiver of a member
+// expression, including the ones inherited through its prototype chain.
+func (da *DebugAdapter) memberCompletions(receiver string, frameID int) []CompletionItem {
+	// Typing in the console must never run code, so only plain paths like
+	// `obj.items[0]` are evaluated, without getters, and only when they mean
+	// the same in the frame
+	expr, err := parseExpression(receiver)
+	if err != nil || !isPropertyPath(expr) {
+		return nil
+	}
+	if err := da.checkFrameEvaluation(receiver, frameID); err != nil {
+		return nil
+	}
+
+	val, err := da.evaluateWithoutSideEffects(receiver, frameID)
+	if err != nil || val == nil || goja.IsUndefined(val) || goja.IsNull(val) {
+		return nil
+	}
+
+	var items []CompletionItem
+	seen := make(map[string]bool)
+	for obj := val.ToObject(da.vm); obj != nil; obj = obj.Prototype() {
+		// The traps of a proxy are script code
+		if obj.ExportType() == proxyType {
+			break
+		}
+		items = append(items, da.propertyCompletions(obj, seen, "property")...)
+	}
+
+	return items
+}
+
+var proxyType = reflect.TypeOf(goja.Proxy{})
+
+// propertyCompletions lists the own properties of obj that aren't in seen
+// yet, and adds them to it. Values are read from the property descriptors,
+// so getters don't run.
+func (da *DebugAdapter) propertyCompletions(obj *goja.Object, seen map[string]bool, kind string) []CompletionItem {
+	var items []CompletionItem
+	for _, name := range obj.GetOwnPropertyNames() {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var val goja.Value
+		if desc, err := da.propertyDescriptor(obj, name); err == nil && desc != nil {
+			val = desc.Get("value")
+		}
+		items = append(items, CompletionItem{Label: name, Type: da.completionType(val, kind)})
+	}
+	return items
+}
+
+// completionType picks the item kind shown next to a completion.
+func (da *DebugAdapter) completionType(val goja.Value, fallback string) string {
+	if obj, ok := val.(*goja.Object); ok {
+		if _, isFunc := goja.AssertFunction(obj); isFunc {
+			if fallback == "property" {
+				return "method"
+			}
+			return "function"
+		}
+	}
+	return fallback
+}
+
+// completionText returns the console input up to the cursor. line and column
+// are 1-based, a zero line means the text has a single line.
+func completionText(text string, line, column int) string {
+	if line > 1 {
+		lines := strings.Split(text, "\n")
+		if line <= len(lines) {
+			text = lines[line-1]
+		}
+	}
+	if column > 0 && column-1 <= len(text) {
+		text = text[:column-1]
+	}
+	return text
+}
+
+// trailingIdentifier returns the identifier being typed at the end of text.
+func trailingIdentifier(text string) string {
+	i := len(text)
+	for i > 0 && isIdentifierChar(text[i-1]) {
+		i--
+	}
+	return text[i:]
+}
+
+// receiverExpression returns the member expression that ends text, e.g.
+// "cfg.items[0]" for "console.log(cfg.items[0]".
+func receiverExpression(text string) string {
+	depth := 0
+	i := len(text)
+	for i > 0 {
+		c := text[i-1]
+		switch {
+		case c == ']' || c == ')':
+			depth++
+		case c == '[' || c == '(':
+			if depth == 0 {
+				return text[i:]
+			}
+			depth--
+		case depth == 0 && !isIdentifierChar(c) && c != '.':
+			return text[i:]
+		}
+		i--
+	}
+	return text
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// isPropertyPath reports whether expr only reads variables and properties,
+// e.g. `this.items[0].name`.
+func isPropertyPath(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.Identifier, *ast.ThisExpression:
+		return true
+	case *ast.DotExpression:
+		return isPropertyPath(e.Left)
+	case *ast.BracketExpression:
+		switch e.Member.(type) {
+		case *ast.StringLiteral, *ast.NumberLiteral, *ast.Identifier:
+			return isPropertyPath(e.Left)
+		}
+	}
+	return false
+}
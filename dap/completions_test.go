@@ -0,0 +1,95 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// completions returns the labels offered for text typed in the console.
+func (s *testSession) completions(text string) []string {
+	s.t.Helper()
+
+	var body CompletionsResponseBody
+	s.request("completions", CompletionsArguments{FrameID: 1, Text: text, Column: len(text) + 1}, &body)
+	labels := []string{}
+	for _, item := range body.Targets {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+func TestMemberCompletionsKeepBreakpoints(t *testing.T) {
+	s := newTestSession(t, `var reads = 0;
+var cfg = { alpha: 1, beta: 2 };
+Object.defineProperty(cfg, "counted", { get: function () { reads++; return 3; }, enumerable: true });
+for (var i = 0; i < 2; i++) {
+  cfg.alpha += i;
+}
+`)
+
+	s.setBreakpoints(SourceBreakpoint{Line: 5})
+	s.request("configurationDone", nil, nil)
+
+	s.stopped()
+	offered := make(map[string]bool)
+	for _, label := range s.completions("cfg.") {
+		offered[label] = true
+	}
+	for _, name := range []string{"alpha", "beta", "counted", "hasOwnProperty"} {
+		if !offered[name] {
+			t.Errorf("%s is not offered for cfg.", name)
+		}
+	}
+	if got := s.completions("cfg.al"); !reflect.DeepEqual(got, []string{"alpha"}) {
+		t.Errorf("completions for cfg.al = %v, want [alpha]", got)
+	}
+
+	// The breakpoint still stops on the next iteration
+	s.resume()
+	if _, frame := s.stopped(); frame.Line != 5 {
+		t.Errorf("stopped at line %d after completing, want 5", frame.Line)
+	}
+	if got := s.evaluate("reads"); got != "0" {
+		t.Errorf("completing ran the getter %s times", got)
+	}
+	s.resume()
+	s.terminated()
+}
+
+func TestScopeCompletions(t *testing.T) {
+	s := newTestSession(t, `var counter = 1;
+function count() {
+  let inner = counter;
+  return inner;
+}
+{
+  let hidden = 2;
+}
+count();
+`)
+
+	s.setBreakpoints(SourceBreakpoint{Line: 4})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	offered := make(map[string]bool)
+	for _, label := range s.completions("") {
+		offered[label] = true
+	}
+	for _, name := range []string{"inner", "counter", "count", "Math"} {
+		if !offered[name] {
+			t.Errorf("%s is not offered in count()", name)
+		}
+	}
+	if offered["hidden"] {
+		t.Error("a block-scoped name outside the frame is offered")
+	}
+	if got := s.completions("cou"); !reflect.DeepEqual(got, []string{"count", "counter"}) {
+		t.Errorf("completions for cou = %v, want [count counter]", got)
+	}
+
+	s.resume()
+	s.terminated()
+}
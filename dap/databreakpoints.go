@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// dataWatch is a data breakpoint on an object property. The property is
+// replaced by an accessor that holds its value and reports every read and
+// write, so execution can pause right after the instruction that touched it.
+type dataWatch struct {
+	obj        *goja.Object
+	name       string
+	value      goja.Value
+	writable   bool
+	enumerable bool
+	getter     *goja.Object
+
+	id           int    // DAP breakpoint ID
+	accessType   string // read, write or readWrite
+	condition    string
+	hitCondition string
+	hits         int
+	active       bool
+}
+
+// dataHit is the access that requested the pause.
+type dataHit struct {
+	watch *dataWatch
+	write bool
+	stack []goja.StackFrame // call stack at the access
+}
+
+func (da *DebugAdapter) handleDataBreakpointInfo(req *Request) {
+	var args DataBreakpointInfoArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	body := DataBreakpointInfoResponseBody{}
+	obj, err := da.dataContainer(args.VariablesReference)
+	var desc *goja.Object
+	if err == nil {
+		restore := da.swapDebugHandler(nil, false)
+		desc, err = da.watchableProperty(obj, args.Name)
+		restore()
+	}
+	switch {
+	case err != nil:
+		body.Description = err.Error()
+	case !desc.Get("writable").ToBoolean():
+		// Writes to a read-only property change nothing, only reads are seen
+		dataID := da.dataObjectID(obj) + ":" + args.Name
+		body.DataID = &dataID
+		body.Description = args.Name + " (read-only, only reads can be watched)"
+		body.AccessTypes = []string{"read"}
+	default:
+		dataID := da.dataObjectID(obj) + ":" + args.Name
+		body.DataID = &dataID
+		body.Description = args.Name
+		body.AccessTypes = []string{"read", "write", "readWrite"}
+	}
+
+	log.Printf("DataBreakpointInfo for ref=%d, name=%s: %s", args.VariablesReference, args.Name, body.Description)
+
+	da.sendResponse(req.Seq, req.Command, true, body)
+}
+
+func (da *DebugAdapter) handleSetDataBreakpoints(req *Request) {
+	var args SetDataBreakpointsArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	log.Printf("SetDataBreakpoints request: %d breakpoints", len(args.Breakpoints))
+
+	// Properties can only be redefined while the VM isn't executing
+	canChange := da.vm != nil && (!da.running || da.isPaused())
+
+	wanted := make(map[string]bool)
+	breakpoints := []Breakpoint{}
+	for _, dbp := range args.Breakpoints {
+		if dbp.AccessType == "" {
+			dbp.AccessType = "write"
+		}
+
+		da.bpMutex.Lock()
+		da.bpIDCounter++
+		bp := Breakpoint{
+			ID:       da.bpIDCounter,
+			Verified: true,
+		}
+		da.bpMutex.Unlock()
+
+		if dbp.HitCondition != "" {
+			if _, err := matchHitCondition(dbp.HitCondition, 0); err != nil {
+				bp.Verified = false
+				bp.Message = err.Error()
+			}
+		}
+
+		if bp.Verified {
+			if err := da.watchProperty(bp.ID, dbp, canChange); err != nil {
+				bp.Verified = false
+				bp.Message = err.Error()
+			} else {
+				wanted[dbp.DataID] = true
+			}
+		}
+
+		log.Printf("Data breakpoint %d: dataId=%s, accessType=%s, verified=%v %s",
+			bp.ID, dbp.DataID, dbp.AccessType, bp.Verified, bp.Message)
+		breakpoints = append(breakpoints, bp)
+	}
+
+	// Drop the watches that are no longer requested
+	da.bpMutex.Lock()
+	var removed []string
+	for dataID, w := range da.dataWatches {
+		if !wanted[dataID] {
+			w.active = false
+			if canChange {
+				removed = append(removed, dataID)
+			}
+		}
+	}
+	da.bpMutex.Unlock()
+
+	for _, dataID := range removed {
+		da.unwatchProperty(dataID)
+	}
+
+	da.sendResponse(req.Seq, req.Command, true, SetBreakpointsResponseBody{
+		Breakpoints: breakpoints,
+	})
+}
+
+// dataContainer returns the object holding the variables of a Variables view
+// reference.
+func (da *DebugAdapter) dataContainer(ref int) (*goja.Object, error) {
+	if da.vm == nil || (da.running && !da.isPaused()) {
+		return nil, fmt.Errorf("data breakpoints can only be added while paused")
+	}
+
+	switch c := da.varRefMap[ref].(type) {
+	case map[string]interface{}:
+		switch c["type"] {
+		case "global":
+			return da.vm.GlobalObject(), nil
+		case "local":
+			return nil, fmt.Errorf("local variables can't be watched, only globals and object properties")
+		}
+	case goja.Value:
+		if obj, ok := c.(*goja.Object); ok {
+			return obj, nil
+		}
+	case *arrayChunk:
+		return c.obj, nil
+	case *frameScope:
+		return nil, fmt.Errorf("%s scope variables can't be watched, only globals and object properties", c.scope.kind)
+	}
+
+	return nil, fmt.Errorf("this value can't be watched")
+}
+
+// watchableProperty returns the descriptor of an own data property that can
+// be turned into an accessor.
+func (da *DebugAdapter) watchableProperty(obj *goja.Object, name string) (*goja.Object, error) {
+	desc, err := da.propertyDescriptor(obj, name)
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil {
+		return nil, fmt.Errorf("'%s' is not an own property and can't be watched", name)
+	}
+
+	if desc.Get("get") != nil || desc.Get("set") != nil {
+		return nil, fmt.Errorf("'%s' has a getter or setter and can't be watched", name)
+	}
+	if !desc.Get("configurable").ToBoolean() {
+		// Top-level var and function declarations are non-configurable
+		// properties of the global object
+		return nil, fmt.Errorf("'%s' is not configurable, like globals declared with var or function, and can't be watched", name)
+	}
+
+	return desc, nil
+}
+
+// propertyDescriptor returns Object.getOwnPropertyDescriptor(obj, name), or
+// nil if obj has no such own property.
+func (da *DebugAdapter) propertyDescriptor(obj *goja.Object, name string) (*goja.Object, error) {
+	getDescriptor, ok := goja.AssertFunction(da.vm.Get("Object").ToObject(da.vm).Get("getOwnPropertyDescriptor"))
+	if !ok {
+		return nil, fmt.Errorf("Object.getOwnPropertyDescriptor is not available")
+	}
+	val, err := getDescriptor(goja.Undefined(), obj, da.vm.ToValue(name))
+	if err != nil {
+		return nil, err
+	}
+	if val == nil || goja.IsUndefined(val) {
+		return nil, nil
+	}
+	return val.ToObject(da.vm), nil
+}
+
+// dataObjectID returns a stable ID for obj, for use in data breakpoint IDs.
+// Variables view references change on every stop, so they can't be used.
+func (da *DebugAdapter) dataObjectID(obj *goja.Object) string {
+	da.bpMutex.Lock()
+	defer da.bpMutex.Unlock()
+
+	for i, o := range da.dataObjects {
+		if o == obj {
+			return strconv.Itoa(i)
+		}
+	}
+	da.dataObjects = append(da.dataObjects, obj)
+	return strconv.Itoa(len(da.dataObjects) - 1)
+}
+
+// watchProperty installs, or updates, the watch for a data breakpoint.
+func (da *DebugAdapter) watchProperty(bpID int, dbp DataBreakpoint, canChange bool) error {
+	da.bpMutex.Lock()
+	w, ok := da.dataWatches[dbp.DataID]
+	if ok && !w.writable && dbp.AccessType != "read" {
+		da.bpMutex.Unlock()
+		return fmt.Errorf("'%s' is read-only, only reads can be watched", w.name)
+	}
+	if ok {
+		w.id = bpID
+		w.accessType = dbp.AccessType
+		w.condition = dbp.Condition
+		w.hitCondition = dbp.HitCondition
+		w.hits = 0
+		w.active = true
+	}
+	da.bpMutex.Unlock()
+	if ok {
+		return nil
+	}
+
+	if !canChange {
+		return fmt.Errorf("data breakpoints can only be added while paused")
+	}
+
+	sep := strings.Index(dbp.DataID, ":")
+	var obj *goja.Object
+	if sep > 0 {
+		if i, err := strconv.Atoi(dbp.DataID[:sep]); err == nil && i >= 0 && i < len(da.dataObjects) {
+			obj = da.dataObjects[i]
+		}
+	}
+	if obj == nil {
+		return fmt.Errorf("unknown data breakpoint %q", dbp.DataID)
+	}
+	name := dbp.DataID[sep+1:]
+
+	// Defining the accessor may run proxy traps
+	defer da.swapDebugHandler(nil, false)()
+
+	desc, err := da.watchableProperty(obj, name)
+	if err != nil {
+		return err
+	}
+	writable := desc.Get("writable").ToBoolean()
+	if !writable && dbp.AccessType != "read" {
+		return fmt.Errorf("'%s' is read-only, only reads can be watched", name)
+	}
+
+	w = &dataWatch{
+		obj:          obj,
+		name:         name,
+		value:        desc.Get("value"),
+		writable:     writable,
+		enumerable:   desc.Get("enumerable").ToBoolean(),
+		id:           bpID,
+		accessType:   dbp.AccessType,
+		condition:    dbp.Condition,
+		hitCondition: dbp.HitCondition,
+		active:       true,
+	}
+	w.getter = da.vm.ToValue(func(goja.FunctionCall) goja.Value {
+		da.dataAccess(w, false)
+		return w.value
+	}).(*goja.Object)
+	// A read-only property gets no setter: assigning to it is then ignored,
+	// or throws in strict code, as it did before
+	var setter goja.Value
+	if w.writable {
+		setter = da.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			w.value = call.Argument(0)
+			da.dataAccess(w, true)
+			return goja.Undefined()
+		})
+	}
+
+	if err := obj.DefineAccessorProperty(name, w.getter, setter, goja.FLAG_TRUE, gojaFlag(w.enumerable)); err != nil {
+		return err
+	}
+
+	da.bpMutex.Lock()
+	da.dataWatches[dbp.DataID] = w
+	da.bpMutex.Unlock()
+	return nil
+}
+
+// unwatchProperty turns a watched property back into a plain data property.
+func (da *DebugAdapter) unwatchProperty(dataID string) {
+	da.bpMutex.Lock()
+	w := da.dataWatches[dataID]
+	delete(da.dataWatches, dataID)
+	da.bpMutex.Unlock()
+
+	if w == nil {
+		return
+	}
+
+	defer da.swapDebugHandler(nil, false)()
+
+	// Leave the property alone if the script deleted or redefined it
+	desc, err := da.propertyDescriptor(w.obj, w.name)
+	if err != nil || desc == nil {
+		return
+	}
+	if getter, ok := desc.Get("get").(*goja.Object); !ok || getter != w.getter {
+		return
+	}
+
+	if err := w.obj.DefineDataProperty(w.name, w.value, gojaFlag(w.writable), goja.FLAG_TRUE, gojaFlag(w.enumerable)); err != nil {
+		log.Printf("Failed to restore '%s' after removing its data breakpoint: %v", w.name, err)
+	}
+}
+
+// dataAccess is called by a watched property's accessor. A matching access
+// asks the debugger to pause before the next instruction.
+func (da *DebugAdapter) dataAccess(w *dataWatch, write bool) {
+	// The adapter's own reads while handling a pause don't count
+	if !da.running || da.inDebugHandler || da.dataHit != nil {
+		return
+	}
+
+	da.bpMutex.Lock()
+	matched := w.active && (w.accessType == "readWrite" || (w.accessType == "write") == write)
+	da.bpMutex.Unlock()
+
+	if matched {
+		da.dataHit = &dataHit{
+			watch: w,
+			write: write,
+			stack: da.vm.CaptureCallStack(0, nil),
+		}
+		da.debugger.Pause()
+	}
+}
+
+// dataBreakpointStopReason checks whether the pause was requested by a data
+// breakpoint and whether its options let it stop. It returns the DAP stop
+// reason, or "". A hit that stops is kept until execution resumes.
+func (da *DebugAdapter) dataBreakpointStopReason() string {
+	hit := da.dataHit
+	if hit == nil {
+		return ""
+	}
+	da.dataHit = nil
+
+	da.bpMutex.Lock()
+	w := hit.watch
+	bpID, condition, hitCondition := w.id, w.condition, w.hitCondition
+	da.bpMutex.Unlock()
+
+	if condition != "" && !da.conditionHolds(bpID, condition) {
+		return ""
+	}
+
+	da.bpMutex.Lock()
+	w.hits++
+	hits := w.hits
+	da.bpMutex.Unlock()
+
+	if hitCondition != "" {
+		matched, err := matchHitCondition(hitCondition, hits)
+		if err != nil || !matched {
+			log.Printf("Data breakpoint %d hit %d times, hit condition %q not met", bpID, hits, hitCondition)
+			return ""
+		}
+	}
+
+	access := "Read"
+	if hit.write {
+		access = "Write"
+	}
+	log.Printf("%s of '%s' hit data breakpoint %d", access, w.name, bpID)
+
+	da.dataHit = hit
+	return "data breakpoint"
+}
+
+func gojaFlag(b bool) goja.Flag {
+	if b {
+		return goja.FLAG_TRUE
+	}
+	return goja.FLAG_FALSE
+}
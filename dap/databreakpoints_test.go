@@ -0,0 +1,74 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"testing"
+)
+
+// dataBreakpointInfo asks whether name in a Variables view container can be
+// watched.
+func (s *testSession) dataBreakpointInfo(ref int, name string) DataBreakpointInfoResponseBody {
+	s.t.Helper()
+
+	var body DataBreakpointInfoResponseBody
+	s.request("dataBreakpointInfo", DataBreakpointInfoArguments{VariablesReference: ref, Name: name}, &body)
+	return body
+}
+
+func TestDataBreakpoint(t *testing.T) {
+	s := newTestSession(t, `var state = { count: 0 };
+total = 0;
+var declared = 1;
+let scoped = 2;
+console.log("ready");
+state.count = 5;
+var seen = state.count;
+total = seen + 1;
+`)
+	s.setBreakpoints(SourceBreakpoint{Line: 5})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	var state EvaluateResponseBody
+	s.request("evaluate", EvaluateArguments{Expression: "state", FrameID: 1, Context: "watch"}, &state)
+	refs := s.scopes(1)
+	count := s.dataBreakpointInfo(state.VariablesReference, "count")
+	total := s.dataBreakpointInfo(refs["Global"], "total")
+	if count.DataID == nil || total.DataID == nil {
+		t.Fatalf("a property or an undeclared global can't be watched: %q, %q", count.Description, total.Description)
+	}
+
+	// Declared globals aren't configurable, script scope names aren't
+	// properties
+	for _, c := range []struct {
+		ref  int
+		name string
+	}{{refs["Global"], "declared"}, {refs["Script"], "scoped"}} {
+		if info := s.dataBreakpointInfo(c.ref, c.name); info.DataID != nil || info.Description == "" {
+			t.Errorf("%s can be watched: %+v", c.name, info)
+		}
+	}
+
+	s.request("setDataBreakpoints", SetDataBreakpointsArguments{Breakpoints: []DataBreakpoint{
+		{DataID: *count.DataID, AccessType: "write"},
+		{DataID: *total.DataID, AccessType: "write"},
+	}}, nil)
+	s.resume()
+
+	// Right after each write, reading count doesn't stop
+	for _, want := range []struct {
+		line              int
+		expression, value string
+	}{{6, "state.count", "5"}, {8, "total", "6"}} {
+		stop, frame := s.stopped()
+		if stop.Reason != "data breakpoint" || frame.Line != want.line {
+			t.Errorf("stopped for %q at line %d; want data breakpoint at line %d", stop.Reason, frame.Line, want.line)
+		}
+		if got := s.evaluate(want.expression); got != want.value {
+			t.Errorf("%s = %s, want %s", want.expression, got, want.value)
+		}
+		s.resume()
+	}
+	s.terminated()
+}
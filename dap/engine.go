@@ -0,0 +1,33 @@
+package main
+
+import "github.com/dop251/goja"
+
+// The goja debugger API differs between the engines the adapter builds
+// against, the build tag picks the files that bridge it:
+//
+//	(none)         the published goja fork: breakpoints and stepping. It
+//	               can't read the scopes of a paused frame, so only script
+//	               and global variables are shown, and expressions are
+//	               evaluated against the global scope when checkFrameEvaluation
+//	               finds that the frame sees the same names
+//	gojalocals     a fork that also has the frame API (GetLocalVariables,
+//	               GetArguments, GetThis, EvaluateInFrame): locals, arguments
+//	               and closures are read and expressions evaluated in the
+//	               selected frame
+//	gojaupstream   upstream goja, which has no debugger: programs run without
+//	               ever pausing
+//
+// Switch the goja replace directive in go.mod to match the tag.
+
+// frameInspector reads the scopes of paused frames. Frames are numbered from
+// the top of the stack, 0 is the innermost one.
+type frameInspector interface {
+	// canReadFrames reports whether locals, arguments and closures of a
+	// frame are in reach. When they aren't, the methods below return nil and
+	// evaluation runs against the global scope.
+	canReadFrames() bool
+	localVariables(frame *goja.StackFrame) map[string]goja.Value
+	arguments(frame *goja.StackFrame) []goja.Value
+	this(frame *goja.StackFrame) goja.Value
+	evaluateInFrame(vm *goja.Runtime, expression string, frameIndex int) (goja.Value, error)
+}
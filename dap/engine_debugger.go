@@ -0,0 +1,25 @@
+//go:build !gojaupstream
+
+package main
+
+import "github.com/dop251/goja"
+
+// hasDebugger reports whether the engine can pause programs.
+const hasDebugger = true
+
+type (
+	engineDebugger = goja.Debugger
+	debugState     = goja.DebuggerState
+	debugCommand   = goja.DebugCommand
+)
+
+const (
+	debugContinue = goja.DebugContinue
+	debugStepOver = goja.DebugStepOver
+	debugStepInto = goja.DebugStepInto
+	debugStepOut  = goja.DebugStepOut
+)
+
+func enableDebugger(vm *goja.Runtime) *engineDebugger {
+	return vm.EnableDebugger()
+}
@@ -0,0 +1,25 @@
+//go:build !gojalocals || gojaupstream
+
+package main
+
+import "github.com/dop251/goja"
+
+// globalInspector is used with engines that can't read frame scopes. Only
+// the global scope is in reach.
+type globalInspector struct{}
+
+func newFrameInspector() frameInspector {
+	return globalInspector{}
+}
+
+func (globalInspector) canReadFrames() bool { return false }
+
+func (globalInspector) localVariables(frame *goja.StackFrame) map[string]goja.Value { return nil }
+
+func (globalInspector) arguments(frame *goja.StackFrame) []goja.Value { return nil }
+
+func (globalInspector) this(frame *goja.StackFrame) goja.Value { return nil }
+
+func (globalInspector) evaluateInFrame(vm *goja.Runtime, expression string, frameIndex int) (goja.Value, error) {
+	return vm.RunString(expression)
+}
@@ -0,0 +1,31 @@
+//go:build gojalocals && !gojaupstream
+
+package main
+
+import "github.com/dop251/goja"
+
+// localsInspector reads frames through the frame API of goja forks that
+// have one.
+type localsInspector struct{}
+
+func newFrameInspector() frameInspector {
+	return localsInspector{}
+}
+
+func (localsInspector) canReadFrames() bool { return true }
+
+func (localsInspector) localVariables(frame *goja.StackFrame) map[string]goja.Value {
+	return frame.GetLocalVariables()
+}
+
+func (localsInspector) arguments(frame *goja.StackFrame) []goja.Value {
+	return frame.GetArguments()
+}
+
+func (localsInspector) this(frame *goja.StackFrame) goja.Value {
+	return frame.GetThis()
+}
+
+func (localsInspector) evaluateInFrame(vm *goja.Runtime, expression string, frameIndex int) (goja.Value, error) {
+	return vm.EvaluateInFrame(expression, frameIndex)
+}
@@ -0,0 +1,136 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+// testFrames stands in for an engine with the frame API. The test program
+// publishes the scope of each function it pauses in, by function name, in a
+// global frames object:
+//
+//	{locals: {...}, args: arguments, self: this, eval: (s) => eval(s)}
+type testFrames struct {
+	da *DebugAdapter
+}
+
+// newFramesTestSession is newTestSession with testFrames as the frame
+// inspector.
+func newFramesTestSession(t *testing.T, src string) *testSession {
+	t.Helper()
+	return startTestSession(t, src, sessionOptions{setup: func(da *DebugAdapter) {
+		da.frames = &testFrames{da: da}
+	}})
+}
+
+func (f *testFrames) canReadFrames() bool { return true }
+
+// scope returns what the program published for frame, or nil.
+func (f *testFrames) scope(frame *goja.StackFrame) *goja.Object {
+	frames, ok := f.da.vm.Get("frames").(*goja.Object)
+	if !ok {
+		return nil
+	}
+	scope, _ := frames.Get(frame.FuncName()).(*goja.Object)
+	return scope
+}
+
+func (f *testFrames) localVariables(frame *goja.StackFrame) map[string]goja.Value {
+	scope := f.scope(frame)
+	if scope == nil {
+		return nil
+	}
+	locals := scope.Get("locals").ToObject(f.da.vm)
+	vars := make(map[string]goja.Value)
+	for _, name := range locals.Keys() {
+		vars[name] = locals.Get(name)
+	}
+	return vars
+}
+
+func (f *testFrames) arguments(frame *goja.StackFrame) []goja.Value {
+	scope := f.scope(frame)
+	if scope == nil {
+		return nil
+	}
+	args := scope.Get("args").ToObject(f.da.vm)
+	values := make([]goja.Value, args.Get("length").ToInteger())
+	for i := range values {
+		values[i] = args.Get(fmt.Sprint(i))
+	}
+	return values
+}
+
+func (f *testFrames) this(frame *goja.StackFrame) goja.Value {
+	if scope := f.scope(frame); scope != nil {
+		return scope.Get("self")
+	}
+	return nil
+}
+
+// evaluateInFrame evaluates expression with the eval arrow function the
+// program published, which sees the frame's scopes.
+func (f *testFrames) evaluateInFrame(vm *goja.Runtime, expression string, frameIndex int) (goja.Value, error) {
+	stack := f.da.pausedStack()
+	if frameIndex >= len(stack) {
+		return nil, fmt.Errorf("frame %d is not on the stack", frameIndex)
+	}
+	scope := f.scope(&stack[frameIndex])
+	if scope == nil {
+		return vm.RunString(expression)
+	}
+	eval, ok := goja.AssertFunction(scope.Get("eval"))
+	if !ok {
+		return nil, fmt.Errorf("%s published no eval", stack[frameIndex].FuncName())
+	}
+	return eval(goja.Undefined(), vm.ToValue(expression))
+}
+
+const framesProgram = `var frames = {};
+var name = "global";
+function greet(name) {
+  var shout = name.toUpperCase();
+  frames.greet = { locals: { name: name, shout: shout }, args: arguments, self: this, eval: (s) => eval(s) };
+  console.log(shout);
+}
+greet.call({ id: 7 }, "bob");
+greet.call({ id: 8 }, "ada", 1);
+`
+
+func TestEvaluateInFrame(t *testing.T) {
+	s := newFramesTestSession(t, framesProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 6, Condition: `name == "ada"`})
+	s.request("configurationDone", nil, nil)
+
+	// The condition reads the parameter, not the global
+	if _, frame := s.stopped(); frame.Line != 6 {
+		t.Fatalf("stopped at line %d, want 6", frame.Line)
+	}
+	tests := []struct {
+		context, expression, want string
+	}{
+		{"watch", "name.length + arguments.length", "5"},
+		{"hover", "this.id", "8"},
+		{"repl", "shout", "ADA"},
+	}
+	for _, tt := range tests {
+		var body EvaluateResponseBody
+		s.request("evaluate", EvaluateArguments{Expression: tt.expression, FrameID: 1, Context: tt.context}, &body)
+		if body.Result != tt.want {
+			t.Errorf("%s %s = %s, want %s", tt.context, tt.expression, body.Result, tt.want)
+		}
+	}
+	if result, ok := s.hover("shout = 1"); ok {
+		t.Errorf("hover assigned a local: %s", result)
+	}
+
+	s.resume()
+	s.terminated()
+	if out := s.output("stderr"); out != "" {
+		t.Errorf("the condition was reported: %s", out)
+	}
+}
@@ -0,0 +1,65 @@
+//go:build gojaupstream
+
+package main
+
+import "github.com/dop251/goja"
+
+// Upstream goja has no debugger. These stand-ins take the adapter's calls
+// and never pause, so programs run to completion and the session still gets
+// their output.
+
+const hasDebugger = false
+
+type debugCommand int
+
+const (
+	debugContinue debugCommand = iota
+	debugStepOver
+	debugStepInto
+	debugStepOut
+)
+
+type debugPosition struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+type debugBreakpoint struct {
+	id int
+}
+
+func (b *debugBreakpoint) ID() int {
+	return b.id
+}
+
+type debugState struct {
+	PC         int
+	SourcePos  debugPosition
+	CallStack  []goja.StackFrame
+	Breakpoint *debugBreakpoint
+	StepMode   bool
+}
+
+type engineDebugger struct {
+	breakpoints int
+}
+
+func enableDebugger(vm *goja.Runtime) *engineDebugger {
+	return &engineDebugger{}
+}
+
+func (d *engineDebugger) SetHandler(handler func(state *debugState) debugCommand) {}
+
+func (d *engineDebugger) SetStepMode(enabled bool) {}
+
+func (d *engineDebugger) AddBreakpoint(filename string, line, column int) int {
+	d.breakpoints++
+	return d.breakpoints
+}
+
+func (d *engineDebugger) RemoveBreakpoint(id int) bool {
+	return true
+}
+
+func (d *engineDebugger) Pause() {}
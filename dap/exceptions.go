@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+)
+
+// exceptionFilters are the exception breakpoint filters shown in the
+// client's Breakpoints view.
+var exceptionFilters = []ExceptionBreakpointsFilter{
+	{
+		Filter:      "all",
+		Label:       "All Exceptions",
+		Description: "Stop at every throw statement, caught or not. Errors raised by the engine, like a TypeError, only stop once uncaught",
+	},
+	{
+		Filter:      "uncaught",
+		Label:       "Uncaught Exceptions",
+		Description: "Stop at throw statements nothing catches, and at other errors once they escape the script",
+	},
+}
+
+// throwSite is a throw statement exception breakpoints stop at, before its
+// argument is evaluated.
+type throwSite struct {
+	prg  *ast.Program
+	stmt *ast.ThrowStatement
+	pos  file.Position // first position compiled for the statement
+}
+
+// exceptionState describes the exception execution is stopped on.
+type exceptionState struct {
+	value       goja.Value // nil when the thrown value could not be evaluated safely
+	description string
+	stack       []goja.StackFrame
+	uncaught    bool
+}
+
+func (da *DebugAdapter) handleSetExceptionBreakpoints(req *Request) {
+	var args SetExceptionBreakpointsArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	log.Printf("SetExceptionBreakpoints request, filters: %v", args.Filters)
+
+	da.bpMutex.Lock()
+	da.exceptionFilters = make(map[string]bool)
+	for _, f := range args.Filters {
+		da.exceptionFilters[f] = true
+	}
+	da.bpMutex.Unlock()
+
+	da.placeThrowBreakpoints()
+
+	da.sendResponse(req.Seq, req.Command, true, nil)
+
+	da.watchStopPoints()
+}
+
+func (da *DebugAdapter) handleExceptionInfo(req *Request) {
+	exc := da.exception
+	if exc == nil {
+		da.sendResponse(req.Seq, req.Command, false, map[string]string{
+			"error": "Not stopped on an exception",
+		})
+		return
+	}
+
+	breakMode := "always"
+	if exc.uncaught {
+		breakMode = "unhandled"
+	}
+
+	exceptionID, typeName := "Exception", ""
+	message := exc.description
+	if obj, ok := exc.value.(*goja.Object); ok {
+		if name := obj.Get("name"); name != nil && !goja.IsUndefined(name) {
+			exceptionID = name.String()
+			typeName = exceptionID
+		}
+		if msg := obj.Get("message"); msg != nil && !goja.IsUndefined(msg) {
+			message = msg.String()
+		}
+	}
+
+	var stack bytes.Buffer
+	for _, frame := range exc.stack {
+		stack.WriteString("\tat ")
+		frame.Write(&stack)
+		stack.WriteByte('\n')
+	}
+
+	da.sendResponse(req.Seq, req.Command, true, ExceptionInfoResponseBody{
+		ExceptionID: exceptionID,
+		Description: exc.description,
+		BreakMode:   breakMode,
+		Details: &ExceptionDetails{
+			Message:    message,
+			TypeName:   typeName,
+			StackTrace: stack.String(),
+		},
+	})
+}
+
+// placeThrowBreakpoints finds the throw statements the exception filters
+// need. Like source breakpoints they are matched by the debug handler, so we
+// stop before the stack unwinds. Errors raised by the engine have no
+// statement to stop at, they are only seen once they escape the script.
+func (da *DebugAdapter) placeThrowBreakpoints() {
+	da.bpMutex.Lock()
+	enabled := len(da.exceptionFilters) > 0
+	da.bpMutex.Unlock()
+
+	var sites []*throwSite
+	if enabled && hasDebugger {
+		da.scriptsMutex.Lock()
+		programs := make([]*ast.Program, 0, len(da.scriptASTs))
+		for _, prg := range da.scriptASTs {
+			programs = append(programs, prg)
+		}
+		da.scriptsMutex.Unlock()
+
+		for _, prg := range programs {
+			var stmts []*ast.ThrowStatement
+			walkAST(prg, func(node ast.Node) bool {
+				if stmt, ok := node.(*ast.ThrowStatement); ok {
+					stmts = append(stmts, stmt)
+				}
+				return true
+			})
+			if len(stmts) == 0 {
+				continue
+			}
+
+			positions := da.sourcePositions(Source{Path: prg.File.Name()})
+			for _, stmt := range stmts {
+				start := nodePosition(prg, stmt.Idx0())
+				pos, ok := resolvePosition(positions, start.Line, start.Column)
+				if !ok || !positionBefore(pos, nodePosition(prg, stmt.Idx1())) {
+					continue
+				}
+				sites = append(sites, &throwSite{prg: prg, stmt: stmt, pos: pos})
+			}
+		}
+	}
+
+	da.bpMutex.Lock()
+	da.throwSites = sites
+	da.rebuildStopTable()
+	da.bpMutex.Unlock()
+}
+
+// throwStopReason is called when execution reaches a throw statement. It
+// records the exception and returns "exception" if the active filters ask to
+// stop on it.
+func (da *DebugAdapter) throwStopReason(site *throwSite) string {
+	stack := da.vm.CaptureCallStack(0, nil)
+	uncaught := !da.isCaught(site.prg, site.stmt.Idx0(), stack)
+
+	da.bpMutex.Lock()
+	stop := da.exceptionFilters["all"] || (uncaught && da.exceptionFilters["uncaught"])
+	da.bpMutex.Unlock()
+
+	if !stop {
+		return ""
+	}
+
+	// The throw hasn't run yet, so only evaluate arguments that can't
+	// change program state, and only if the engine sees the same names the
+	// frame does
+	arg := site.stmt.Argument
+	source := nodeSource(site.prg, arg)
+	exc := &exceptionState{
+		description: source,
+		stack:       stack,
+		uncaught:    uncaught,
+	}
+	if isSafeThrowArgument(arg) && da.checkFrameEvaluation(source, 1) == nil {
+		val, err := da.evaluateWithoutSideEffects(source, 1)
+		if err == nil {
+			exc.value = val
+			exc.description = da.describeException(val)
+		}
+	}
+
+	da.exception = exc
+	// Don't stop a second time when the same exception escapes the script
+	da.uncaughtReported = uncaught
+	return "exception"
+}
+
+// reportUncaughtException stops on an exception that escaped the script, if
+// the filters ask for it. The VM has already unwound, so only the stack
+// recorded in the exception is left to show.
+func (da *DebugAdapter) reportUncaughtException(err error) {
+	var ex *goja.Exception
+	if !errors.As(err, &ex) {
+		return
+	}
+
+	da.bpMutex.Lock()
+	stop := da.exceptionFilters["all"] || da.exceptionFilters["uncaught"]
+	da.bpMutex.Unlock()
+
+	if !stop || da.uncaughtReported {
+		return
+	}
+
+	da.exception = &exceptionState{
+		value:       ex.Value(),
+		description: da.describeException(ex.Value()),
+		stack:       ex.Stack(),
+		uncaught:    true,
+	}
+
+	da.debugStateMutex.Lock()
+	da.waitingForCmd = true
+	commandReady := da.commandReady
+	da.debugStateMutex.Unlock()
+
+	da.sendEvent("stopped", StoppedEventBody{
+		Reason:            "exception",
+		Description:       "Uncaught exception",
+		ThreadID:          da.threadID,
+		Text:              da.exception.description,
+		AllThreadsStopped: true,
+	})
+
+	log.Printf("Stopped on uncaught exception, waiting for debugger command...")
+	<-commandReady
+	da.exception = nil
+	da.forgetPausedStack()
+}
+
+// callStack returns the frames to show in the Call Stack view. After an
+// uncaught exception the VM stack is gone, so the exception's stack is used.
+// A data breakpoint shows the stack of the access, the VM has already moved
+// on to the next instruction.
+func (da *DebugAdapter) callStack(depth int) []goja.StackFrame {
+	var stack []goja.StackFrame
+	switch {
+	case !da.running && da.exception != nil:
+		stack = da.exception.stack
+	case da.dataHit != nil:
+		stack = da.dataHit.stack
+	default:
+		return da.vm.CaptureCallStack(depth, nil)
+	}
+	if depth > 0 && len(stack) > depth {
+		stack = stack[:depth]
+	}
+	return stack
+}
+
+// describeException renders a thrown value for the stopped event and the
+// exceptionInfo description, e.g. "TypeError: x is not a function".
+func (da *DebugAdapter) describeException(val goja.Value) string {
+	if obj, ok := val.(*goja.Object); ok {
+		if msg := obj.Get("message"); msg != nil && !goja.IsUndefined(msg) {
+			return obj.String()
+		}
+	}
+	return da.formatLogValue(val)
+}
+
+// exceptionVariables lists the contents of the Exception scope.
+func (da *DebugAdapter) exceptionVariables() []Variable {
+	exc := da.exception
+	if exc == nil {
+		return nil
+	}
+
+	obj, ok := exc.value.(*goja.Object)
+	if !ok {
+		return []Variable{{
+			Name:               "exception",
+			Value:              exc.description,
+			Type:               "exception",
+			VariablesReference: 0,
+		}}
+	}
+
+	// Error properties are not enumerable, so list them explicitly
+	var variables []Variable
+	for _, key := range []string{"name", "message", "stack"} {
+		if val := obj.Get(key); val != nil && !goja.IsUndefined(val) {
+			variables = append(variables, Variable{
+				Name:               key,
+				Value:              val.String(),
+				Type:               da.getValueType(val),
+				VariablesReference: 0,
+			})
+		}
+	}
+
+	return append(variables, da.getObjectProperties(obj)...)
+}
+
+// isCaught reports whether a throw at idx would be caught, either by an
+// enclosing try/catch in the same function or by one around the call site
+// in any of the calling frames.
+func (da *DebugAdapter) isCaught(prg *ast.Program, idx file.Idx, stack []goja.StackFrame) bool {
+	if insideTryCatch(prg, idx) {
+		return true
+	}
+
+	da.scriptsMutex.Lock()
+	defer da.scriptsMutex.Unlock()
+
+	// stack[0] is the frame doing the throw
+	for i := 1; i < len(stack); i++ {
+		pos := stack[i].Position()
+		callerPrg, ok := da.scriptASTs[pos.Filename]
+		if !ok {
+			continue
+		}
+		if insideTryCatch(callerPrg, positionIdx(callerPrg, pos.Line, pos.Column)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// insideTryCatch reports whether idx lies in the body of a try statement
+// with a catch clause in the same function.
+func insideTryCatch(prg *ast.Program, idx file.Idx) bool {
+	caught := false
+
+	walkAST(prg, func(node ast.Node) bool {
+		if _, isProgram := node.(*ast.Program); !isProgram && (idx < node.Idx0() || idx >= node.Idx1()) {
+			return false
+		}
+
+		switch n := node.(type) {
+		case *ast.FunctionLiteral, *ast.ArrowFunctionLiteral:
+			// A try outside the function only catches if it surrounds the
+			// call, which is checked on the caller's frame
+			caught = false
+		case *ast.TryStatement:
+			if n.Catch != nil && idx >= n.Body.Idx0() && idx < n.Body.Idx1() {
+				caught = true
+			}
+		}
+		return true
+	})
+
+	return caught
+}
+
+// positionIdx converts a 1-based line/column back into a parser index.
+func positionIdx(prg *ast.Program, line, column int) file.Idx {
+	src := prg.File.Source()
+	offset := 0
+	for l := 1; l < line; l++ {
+		next := strings.IndexByte(src[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+	}
+	return file.Idx(prg.File.Base() + offset + column - 1)
+}
+
+// nodeSource returns the source text of a node.
+func nodeSource(prg *ast.Program, node ast.Node) string {
+	src := prg.File.Source()
+	start := int(node.Idx0()) - prg.File.Base()
+	end := int(node.Idx1()) - prg.File.Base()
+	if start < 0 || end > len(src) || start > end {
+		return ""
+	}
+	return src[start:end]
+}
+
+// builtinErrors are the error constructors a thrown `new X(...)` may name
+// and still be evaluated ahead of the throw.
+var builtinErrors = map[string]bool{
+	"Error":          true,
+	"EvalError":      true,
+	"RangeError":     true,
+	"ReferenceError": true,
+	"SyntaxError":    true,
+	"TypeError":      true,
+	"URIError":       true,
+	"AggregateError": true,
+}
+
+// isSafeThrowArgument reports whether a thrown expression is simple enough
+// to evaluate ahead of the throw, e.g. `err`, `"failed"` or
+// `new Error(`failed ${id}`)`. Member reads and operators are left out, they
+// can run getters or valueOf, and only the built-in error constructors may be
+// called. Converting a value to a string can still run its toString, so the
+// expression is evaluated without side effects as well.
+func isSafeThrowArgument(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.NewExpression:
+		callee, ok := e.Callee.(*ast.Identifier)
+		if !ok || !builtinErrors[callee.Name.String()] {
+			return false
+		}
+		for _, arg := range e.ArgumentList {
+			if !isSafeThrowOperand(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return isSafeThrowOperand(expr)
+}
+
+// isSafeThrowOperand reports whether an expression only reads a name or is a
+// literal, or an untagged template made of those.
+func isSafeThrowOperand(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.Identifier, *ast.StringLiteral, *ast.NumberLiteral,
+		*ast.BooleanLiteral, *ast.NullLiteral, *ast.ThisExpression:
+		return true
+	case *ast.TemplateLiteral:
+		if e.Tag != nil {
+			return false
+		}
+		for _, sub := range e.Expressions {
+			if !isSafeThrowOperand(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
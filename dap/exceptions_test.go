@@ -0,0 +1,88 @@
+//go:build !gojaupstream
+
+package main
+
+import "testing"
+
+const throwingProgram = `function fail(message) {
+  throw new Error(message);
+}
+try {
+  fail("caught");
+} catch (e) {
+  console.log(e.message);
+}
+fail("uncaught");
+`
+
+func TestAllExceptionsStopOnCaughtThrow(t *testing.T) {
+	s := newTestSession(t, throwingProgram)
+
+	s.request("setExceptionBreakpoints", SetExceptionBreakpointsArguments{Filters: []string{"all"}}, nil)
+	s.request("configurationDone", nil, nil)
+
+	stop, frame := s.stopped()
+	if stop.Reason != "exception" || frame.Line != 2 {
+		t.Fatalf("stopped for %q at line %d; want exception at line 2", stop.Reason, frame.Line)
+	}
+	var info ExceptionInfoResponseBody
+	s.request("exceptionInfo", ExceptionInfoArguments{ThreadID: 1}, &info)
+	// Constructing the error could run code, the throw shows its source
+	if info.BreakMode != "always" || info.Description != "new Error(message)" {
+		t.Errorf("exception info = %+v; want break mode always, described by its source", info)
+	}
+	s.resume()
+
+	// The second throw escapes and stops once, at the throw
+	stop, frame = s.stopped()
+	if stop.Reason != "exception" || frame.Line != 2 {
+		t.Errorf("stopped for %q at line %d; want exception at line 2", stop.Reason, frame.Line)
+	}
+	s.request("exceptionInfo", ExceptionInfoArguments{ThreadID: 1}, &info)
+	if info.BreakMode != "unhandled" {
+		t.Errorf("break mode of the escaping exception = %q, want unhandled", info.BreakMode)
+	}
+	s.resume()
+	s.terminated()
+}
+
+func TestUncaughtExceptionsSkipCaughtThrow(t *testing.T) {
+	s := newTestSession(t, throwingProgram)
+
+	s.request("setExceptionBreakpoints", SetExceptionBreakpointsArguments{Filters: []string{"uncaught"}}, nil)
+	s.request("configurationDone", nil, nil)
+
+	stop, frame := s.stopped()
+	if stop.Reason != "exception" || frame.Line != 2 {
+		t.Errorf("stopped for %q at line %d; want exception at line 2", stop.Reason, frame.Line)
+	}
+	if out := s.output("console"); out != "caught\n" {
+		t.Errorf("console output = %q; the caught throw should have run without stopping", out)
+	}
+	s.resume()
+	s.terminated()
+}
+
+func TestUncaughtEngineErrorStops(t *testing.T) {
+	s := newTestSession(t, `var value = null;
+value.missing = 1;
+`)
+
+	s.request("setExceptionBreakpoints", SetExceptionBreakpointsArguments{Filters: []string{"uncaught"}}, nil)
+	s.request("configurationDone", nil, nil)
+
+	stop, _ := s.stopped()
+	if stop.Reason != "exception" {
+		t.Errorf("stopped for %q, want exception", stop.Reason)
+	}
+	s.resume()
+	s.terminated()
+}
+
+func TestNoExceptionFiltersNeverStop(t *testing.T) {
+	s := newTestSession(t, throwingProgram)
+
+	s.request("setExceptionBreakpoints", SetExceptionBreakpointsArguments{Filters: []string{}}, nil)
+	s.request("configurationDone", nil, nil)
+	s.terminated()
+}
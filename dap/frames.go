@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/dop251/goja"
+)
+
+func (da *DebugAdapter) handleStackTrace(req *Request) {
+	var args StackTraceArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	stack := da.pausedStack()
+
+	start := args.StartFrame
+	if start < 0 {
+		start = 0
+	}
+	end := len(stack)
+	if args.Levels > 0 && start+args.Levels < end {
+		end = start + args.Levels
+	}
+
+	frames := []StackFrame{}
+	for i := start; i < end; i++ {
+		// Frame IDs are 1-based positions from the top of the stack
+		frameID := i + 1
+		da.frameMap[frameID] = &stack[i]
+
+		funcName := stack[i].FuncName()
+		if funcName == "" {
+			funcName = "(anonymous)"
+		}
+
+		line, column, endLine, endColumn := da.frameRange(stack, i)
+		frames = append(frames, StackFrame{
+			ID:        frameID,
+			Name:      funcName,
+			Line:      line,
+			Column:    column,
+			EndLine:   endLine,
+			EndColumn: endColumn,
+			Source:    da.frameSource(stack, i),
+		})
+	}
+
+	log.Printf("StackTrace frames %d-%d of %d", start, start+len(frames), len(stack))
+
+	da.sendResponse(req.Seq, req.Command, true, StackTraceResponseBody{
+		StackFrames: frames,
+		TotalFrames: len(stack),
+	})
+}
+
+// frameRange returns the range of code frame i is paused at, with columns
+// counted the way the client asked for in initialize. The end is 0 when the
+// script's AST isn't available.
+func (da *DebugAdapter) frameRange(stack []goja.StackFrame, i int) (line, column, endLine, endColumn int) {
+	pos := stack[i].Position()
+	line, column = pos.Line, pos.Column
+
+	da.scriptsMutex.Lock()
+	prg := da.scriptASTs[pos.Filename]
+	da.scriptsMutex.Unlock()
+
+	if prg != nil && line > 0 {
+		if start, end, ok := pausedRange(prg, positionIdx(prg, pos.Line, pos.Column)); ok {
+			startPos, endPos := nodePosition(prg, start), nodePosition(prg, end)
+			line, column = startPos.Line, startPos.Column
+			endLine, endColumn = endPos.Line, endPos.Column
+		}
+	}
+
+	if column < 1 {
+		column = 1
+	}
+	column = da.clientColumn(column)
+	if endColumn > 0 {
+		endColumn = da.clientColumn(endColumn)
+	}
+	return line, column, endLine, endColumn
+}
+
+// clientColumn converts a 1-based column to the way the client asked for
+// columns to be counted in initialize.
+func (da *DebugAdapter) clientColumn(column int) int {
+	if !da.columnsStartAt1 {
+		return column - 1
+	}
+	return column
+}
+
+// pausedStack returns the whole call stack of the current pause. It is
+// captured once, so every page of it the client asks for, and every frame ID
+// handed out, refers to the same frames.
+func (da *DebugAdapter) pausedStack() []goja.StackFrame {
+	if da.stackFrames == nil {
+		da.stackFrames = da.callStack(0)
+	}
+	return da.stackFrames
+}
+
+// forgetPausedStack drops the stack and frame IDs of a pause once execution
+// resumes.
+func (da *DebugAdapter) forgetPausedStack() {
+	da.stackFrames = nil
+	da.frameMap = make(map[int]*goja.StackFrame)
+}
+
+// handleRestartFrame answers restartFrame requests. The goja debugger can
+// only continue or step from the current instruction, there is no way to
+// unwind to a frame and re-enter it, so SupportsRestartFrame stays false and
+// clients that send the request anyway get an explanation instead of an
+// "unknown command" failure. See GOJA_DEBUG_PROPOSAL.md for the engine
+// support this needs.
+func (da *DebugAdapter) handleRestartFrame(req *Request) {
+	var args RestartFrameArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	log.Printf("RestartFrame request for frame %d: not supported by the goja debugger", args.FrameID)
+
+	da.sendErrorResponse(req, fmt.Errorf("restarting frame %d is not supported: the goja debugger cannot rewind a paused frame", args.FrameID))
+}
@@ -11,5 +11,7 @@ require (
 	golang.org/x/text v0.3.8 // indirect
 )
 
-//replace github.com/dop251/goja => github.com/arturoeanton/goja v0.0.0-20250729040025-e2ff0c5841bb
-replace github.com/dop251/goja => ../../goja/
+// The published fork with the debugger API. For a fork with the frame API,
+// point this at it and build with -tags gojalocals; for upstream goja, drop it
+// and build with -tags gojaupstream (see engine.go).
+replace github.com/dop251/goja => github.com/arturoeanton/goja v0.0.0-20250729040025-e2ff0c5841bb
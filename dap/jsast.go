@@ -0,0 +1,738 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+)
+
+// parseScript parses a script with goja's own parser and remembers the AST
+// so breakpoints, scopes and step targets can be resolved against it.
+func (da *DebugAdapter) parseScript(filename, source string) (*ast.Program, error) {
+	prg, err := parser.ParseFile(nil, filename, source, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	da.scriptsMutex.Lock()
+	da.scriptASTs[filename] = prg
+	da.scriptsMutex.Unlock()
+
+	return prg, nil
+}
+
+// nodePosition converts a parser index into a line/column position.
+func nodePosition(prg *ast.Program, idx file.Idx) file.Position {
+	return prg.File.Position(int(idx) - prg.File.Base())
+}
+
+// walkAST calls visit for node and, as long as visit returns true, for every
+// node below it in source order.
+func walkAST(node ast.Node, visit func(ast.Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+
+	walk := func(n ast.Node) {
+		walkAST(n, visit)
+	}
+	walkExprs := func(list []ast.Expression) {
+		for _, e := range list {
+			if e != nil {
+				walk(e)
+			}
+		}
+	}
+	walkStmts := func(list []ast.Statement) {
+		for _, s := range list {
+			if s != nil {
+				walk(s)
+			}
+		}
+	}
+	walkBindings := func(list []*ast.Binding) {
+		for _, b := range list {
+			if b != nil {
+				walk(b)
+			}
+		}
+	}
+	walkExpr := func(e ast.Expression) {
+		if e != nil {
+			walk(e)
+		}
+	}
+	walkStmt := func(s ast.Statement) {
+		if s != nil {
+			walk(s)
+		}
+	}
+	walkBlock := func(b *ast.BlockStatement) {
+		if b != nil {
+			walk(b)
+		}
+	}
+	walkParams := func(p *ast.ParameterList) {
+		if p != nil {
+			walk(p)
+		}
+	}
+
+	switch n := node.(type) {
+	case *ast.Program:
+		walkStmts(n.Body)
+
+	// Expressions
+	case *ast.ArrayLiteral:
+		walkExprs(n.Value)
+	case *ast.ArrayPattern:
+		walkExprs(n.Elements)
+		walkExpr(n.Rest)
+	case *ast.AssignExpression:
+		walkExpr(n.Left)
+		walkExpr(n.Right)
+	case *ast.AwaitExpression:
+		walkExpr(n.Argument)
+	case *ast.YieldExpression:
+		walkExpr(n.Argument)
+	case *ast.BinaryExpression:
+		walkExpr(n.Left)
+		walkExpr(n.Right)
+	case *ast.BracketExpression:
+		walkExpr(n.Left)
+		walkExpr(n.Member)
+	case *ast.CallExpression:
+		walkExpr(n.Callee)
+		walkExprs(n.ArgumentList)
+	case *ast.ConditionalExpression:
+		walkExpr(n.Test)
+		walkExpr(n.Consequent)
+		walkExpr(n.Alternate)
+	case *ast.DotExpression:
+		walkExpr(n.Left)
+	case *ast.PrivateDotExpression:
+		walkExpr(n.Left)
+	case *ast.OptionalChain:
+		walkExpr(n.Expression)
+	case *ast.Optional:
+		walkExpr(n.Expression)
+	case *ast.FunctionLiteral:
+		walkParams(n.ParameterList)
+		walkBlock(n.Body)
+	case *ast.ArrowFunctionLiteral:
+		walkParams(n.ParameterList)
+		if n.Body != nil {
+			walk(n.Body)
+		}
+	case *ast.ExpressionBody:
+		walkExpr(n.Expression)
+	case *ast.ClassLiteral:
+		walkExpr(n.SuperClass)
+		for _, el := range n.Body {
+			if el != nil {
+				walk(el)
+			}
+		}
+	case *ast.FieldDefinition:
+		if n.Computed {
+			walkExpr(n.Key)
+		}
+		walkExpr(n.Initializer)
+	case *ast.MethodDefinition:
+		if n.Computed {
+			walkExpr(n.Key)
+		}
+		if n.Body != nil {
+			walk(n.Body)
+		}
+	case *ast.ClassStaticBlock:
+		walkBlock(n.Block)
+	case *ast.NewExpression:
+		walkExpr(n.Callee)
+		walkExprs(n.ArgumentList)
+	case *ast.ObjectLiteral:
+		for _, p := range n.Value {
+			walkExpr(p)
+		}
+	case *ast.ObjectPattern:
+		for _, p := range n.Properties {
+			walkExpr(p)
+		}
+		walkExpr(n.Rest)
+	case *ast.PropertyShort:
+		walkExpr(n.Initializer)
+	case *ast.PropertyKeyed:
+		if n.Computed {
+			walkExpr(n.Key)
+		}
+		walkExpr(n.Value)
+	case *ast.SpreadElement:
+		walkExpr(n.Expression)
+	case *ast.ParameterList:
+		walkBindings(n.List)
+		walkExpr(n.Rest)
+	case *ast.Binding:
+		walkExpr(n.Target)
+		walkExpr(n.Initializer)
+	case *ast.SequenceExpression:
+		walkExprs(n.Sequence)
+	case *ast.TemplateLiteral:
+		walkExpr(n.Tag)
+		walkExprs(n.Expressions)
+	case *ast.UnaryExpression:
+		walkExpr(n.Operand)
+
+	// Statements
+	case *ast.BlockStatement:
+		walkStmts(n.List)
+	case *ast.CaseStatement:
+		walkExpr(n.Test)
+		walkStmts(n.Consequent)
+	case *ast.CatchStatement:
+		walkExpr(n.Parameter)
+		walkBlock(n.Body)
+	case *ast.DoWhileStatement:
+		walkStmt(n.Body)
+		walkExpr(n.Test)
+	case *ast.ExpressionStatement:
+		walkExpr(n.Expression)
+	case *ast.ForInStatement:
+		if n.Into != nil {
+			walk(n.Into)
+		}
+		walkExpr(n.Source)
+		walkStmt(n.Body)
+	case *ast.ForOfStatement:
+		if n.Into != nil {
+			walk(n.Into)
+		}
+		walkExpr(n.Source)
+		walkStmt(n.Body)
+	case *ast.ForStatement:
+		if n.Initializer != nil {
+			walk(n.Initializer)
+		}
+		walkExpr(n.Test)
+		walkExpr(n.Update)
+		walkStmt(n.Body)
+	case *ast.ForLoopInitializerExpression:
+		walkExpr(n.Expression)
+	case *ast.ForLoopInitializerVarDeclList:
+		walkBindings(n.List)
+	case *ast.ForLoopInitializerLexicalDecl:
+		walk(&n.LexicalDeclaration)
+	case *ast.ForIntoVar:
+		if n.Binding != nil {
+			walk(n.Binding)
+		}
+	case *ast.ForDeclaration:
+		walkExpr(n.Target)
+	case *ast.ForIntoExpression:
+		walkExpr(n.Expression)
+	case *ast.IfStatement:
+		walkExpr(n.Test)
+		walkStmt(n.Consequent)
+		walkStmt(n.Alternate)
+	case *ast.LabelledStatement:
+		walkStmt(n.Statement)
+	case *ast.ReturnStatement:
+		walkExpr(n.Argument)
+	case *ast.SwitchStatement:
+		walkExpr(n.Discriminant)
+		for _, c := range n.Body {
+			if c != nil {
+				walk(c)
+			}
+		}
+	case *ast.ThrowStatement:
+		walkExpr(n.Argument)
+	case *ast.TryStatement:
+		walkBlock(n.Body)
+		if n.Catch != nil {
+			walk(n.Catch)
+		}
+		walkBlock(n.Finally)
+	case *ast.VariableStatement:
+		walkBindings(n.List)
+	case *ast.LexicalDeclaration:
+		walkBindings(n.List)
+	case *ast.WhileStatement:
+		walkExpr(n.Test)
+		walkStmt(n.Body)
+	case *ast.WithStatement:
+		walkExpr(n.Object)
+		walkStmt(n.Body)
+	case *ast.FunctionDeclaration:
+		if n.Function != nil {
+			walk(n.Function)
+		}
+	case *ast.ClassDeclaration:
+		if n.Class != nil {
+			walk(n.Class)
+		}
+	}
+}
+
+// expressionName renders identifiers and member chains such as
+// "Class.prototype.method" back to source form. It returns "" for anything
+// more complex.
+func expressionName(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Name.String()
+	case *ast.ThisExpression:
+		return "this"
+	case *ast.DotExpression:
+		left := expressionName(e.Left)
+		if left == "" {
+			return ""
+		}
+		return left + "." + e.Identifier.Name.String()
+	case *ast.BracketExpression:
+		left := expressionName(e.Left)
+		if s, ok := e.Member.(*ast.StringLiteral); ok && left != "" {
+			return left + "." + s.Value.String()
+		}
+	}
+	return ""
+}
+
+// propertyKeyName returns the static name of an object or class member key.
+func propertyKeyName(key ast.Expression) string {
+	switch k := key.(type) {
+	case *ast.Identifier:
+		return k.Name.String()
+	case *ast.StringLiteral:
+		return k.Value.String()
+	case *ast.NumberLiteral:
+		return k.Literal
+	}
+	return ""
+}
+
+// jsFunction is a function found in a script together with the names it can
+// be referred to by, e.g. "area", "Shape.prototype.area" and "Shape.area".
+type jsFunction struct {
+	Names []string
+	Node  ast.Node // the function literal
+	Start file.Idx // first statement of the body
+}
+
+// matches reports whether name refers to the function. A bare name matches
+// the last segment, and "Class.method" also matches prototype methods.
+func (f *jsFunction) matches(name string) bool {
+	for _, n := range f.Names {
+		if n == name || strings.Replace(n, ".prototype.", ".", 1) == name {
+			return true
+		}
+		if !strings.Contains(name, ".") && n[strings.LastIndex(n, ".")+1:] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findFunctions lists the named functions of a script: declarations,
+// functions assigned to variables or members, object literal methods and
+// class methods.
+func findFunctions(prg *ast.Program) []*jsFunction {
+	var funcs []*jsFunction
+	byNode := make(map[ast.Node]*jsFunction)
+
+	add := func(node ast.Node, name string) {
+		if name == "" {
+			return
+		}
+		f, ok := byNode[node]
+		if !ok {
+			f = &jsFunction{Node: node, Start: functionBodyStart(node)}
+			byNode[node] = f
+			funcs = append(funcs, f)
+		}
+		for _, n := range f.Names {
+			if n == name {
+				return
+			}
+		}
+		f.Names = append(f.Names, name)
+	}
+
+	var nameValue func(name string, value ast.Expression)
+	var nameClass func(name string, cls *ast.ClassLiteral)
+
+	nameValue = func(name string, value ast.Expression) {
+		switch v := value.(type) {
+		case *ast.FunctionLiteral, *ast.ArrowFunctionLiteral:
+			add(v, name)
+		case *ast.ClassLiteral:
+			nameClass(name, v)
+		case *ast.ObjectLiteral:
+			for _, p := range v.Value {
+				if pk, ok := p.(*ast.PropertyKeyed); ok && !pk.Computed {
+					if key := propertyKeyName(pk.Key); key != "" {
+						nameValue(name+"."+key, pk.Value)
+					}
+				}
+			}
+		}
+	}
+
+	nameClass = func(name string, cls *ast.ClassLiteral) {
+		for _, el := range cls.Body {
+			m, ok := el.(*ast.MethodDefinition)
+			if !ok || m.Computed || m.Body == nil {
+				continue
+			}
+			key := propertyKeyName(m.Key)
+			switch {
+			case key == "":
+			case key == "constructor" && !m.Static:
+				add(m.Body, name)
+			case m.Static:
+				add(m.Body, name+"."+key)
+			default:
+				add(m.Body, name+".prototype."+key)
+			}
+		}
+	}
+
+	walkAST(prg, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionLiteral:
+			if n.Name != nil {
+				add(n, n.Name.Name.String())
+			}
+		case *ast.ClassLiteral:
+			if n.Name != nil {
+				nameClass(n.Name.Name.String(), n)
+			}
+		case *ast.Binding:
+			if id, ok := n.Target.(*ast.Identifier); ok && n.Initializer != nil {
+				nameValue(id.Name.String(), n.Initializer)
+			}
+		case *ast.AssignExpression:
+			if name := expressionName(n.Left); name != "" {
+				nameValue(name, n.Right)
+			}
+		}
+		return true
+	})
+
+	return funcs
+}
+
+// functionBodyStart returns the position of the first statement executed
+// when the function is entered.
+func functionBodyStart(node ast.Node) file.Idx {
+	var body ast.ConciseBody
+	switch f := node.(type) {
+	case *ast.FunctionLiteral:
+		body = f.Body
+	case *ast.ArrowFunctionLiteral:
+		body = f.Body
+	}
+
+	switch b := body.(type) {
+	case *ast.BlockStatement:
+		if len(b.List) > 0 {
+			return b.List[0].Idx0()
+		}
+		return b.RightBrace
+	case *ast.ExpressionBody:
+		return b.Expression.Idx0()
+	}
+	return node.Idx0()
+}
+
+// pausedRange returns the extent of the code a frame is paused at, given
+// goja's position for it. Frames below the top are paused at the opening
+// parenthesis of a call, the range is the call from its name, e.g. `c(x)` in
+// `a.b().c(x)`. Otherwise it is the largest expression or simple statement
+// starting there. ok is false if nothing matches.
+func pausedRange(prg *ast.Program, idx file.Idx) (start, end file.Idx, ok bool) {
+	var call ast.Node
+	var callee ast.Expression
+	var outer ast.Node
+	walkAST(prg, func(node ast.Node) bool {
+		if idx < node.Idx0() || idx >= node.Idx1() {
+			return false
+		}
+		switch n := node.(type) {
+		case *ast.CallExpression:
+			if n.LeftParenthesis == idx {
+				call, callee = n, n.Callee
+			}
+		case *ast.NewExpression:
+			if n.LeftParenthesis == idx {
+				call, callee = n, n.Callee
+			}
+		}
+		if outer == nil && node.Idx0() == idx && isSimpleNode(node) {
+			outer = node
+		}
+		return true
+	})
+
+	switch {
+	case call != nil:
+		start = callee.Idx0()
+		if dot, isDot := callee.(*ast.DotExpression); isDot {
+			start = dot.Identifier.Idx0()
+		}
+		return start, call.Idx1(), true
+	case outer != nil:
+		return outer.Idx0(), outer.Idx1(), true
+	}
+	return 0, 0, false
+}
+
+// isSimpleNode reports whether node is an expression or a statement that
+// doesn't hold other statements.
+func isSimpleNode(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.FunctionLiteral, *ast.ArrowFunctionLiteral, *ast.ClassLiteral:
+		return false
+	case ast.Expression, *ast.Binding,
+		*ast.ExpressionStatement, *ast.VariableStatement, *ast.LexicalDeclaration,
+		*ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	}
+	return false
+}
+
+// lexicalScope is a level of the scope chain at a position in a script,
+// with the names declared directly in it.
+type lexicalScope struct {
+	kind  string // "function", "block", "catch" or "script"
+	name  string // function name, for closures
+	names []string
+}
+
+// declaredScope is a function or block of a script and the source range it
+// covers.
+type declaredScope struct {
+	start, end file.Idx
+	lexicalScope
+}
+
+// declaredScopes maps every function of a script, and every block that
+// declares something, to its bindings by source range, outermost first. var
+// and function declarations belong to their function, at the top level they
+// are properties of the global object and aren't part of the script scope.
+// let, const and class belong to their block. Functions are told apart by
+// range, so two with the same name don't collide.
+func declaredScopes(prg *ast.Program) []declaredScope {
+	scopes := []declaredScope{{
+		start:        prg.Idx0(),
+		end:          prg.Idx1(),
+		lexicalScope: lexicalScope{kind: "script", names: lexicalNames(prg.Body, false)},
+	}}
+	add := func(node ast.Node, scope lexicalScope) {
+		if scope.kind == "function" || len(scope.names) > 0 {
+			scopes = append(scopes, declaredScope{node.Idx0(), node.Idx1(), scope})
+		}
+	}
+	merged := make(map[ast.Node]bool) // blocks already part of a scope
+
+	walkAST(prg, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionLiteral:
+			name := "(anonymous)"
+			if n.Name != nil {
+				name = n.Name.Name.String()
+			}
+			add(n, functionScope(name, n.ParameterList, n.Body))
+			merged[n.Body] = true
+		case *ast.ArrowFunctionLiteral:
+			add(n, functionScope("(anonymous)", n.ParameterList, n.Body))
+			merged[n.Body] = true
+		case *ast.BlockStatement:
+			if !merged[n] {
+				add(n, lexicalScope{kind: "block", names: lexicalNames(n.List, true)})
+			}
+		case *ast.CatchStatement:
+			names := bindingNames(n.Parameter)
+			if n.Body != nil {
+				names = append(names, lexicalNames(n.Body.List, true)...)
+				merged[n.Body] = true
+			}
+			add(n, lexicalScope{kind: "catch", names: names})
+		case *ast.ForStatement:
+			if decl, ok := n.Initializer.(*ast.ForLoopInitializerLexicalDecl); ok {
+				add(n, lexicalScope{kind: "block", names: declarationNames(decl.LexicalDeclaration.List)})
+			}
+		case *ast.ForInStatement:
+			if decl, ok := n.Into.(*ast.ForDeclaration); ok {
+				add(n, lexicalScope{kind: "block", names: bindingNames(decl.Target)})
+			}
+		case *ast.ForOfStatement:
+			if decl, ok := n.Into.(*ast.ForDeclaration); ok {
+				add(n, lexicalScope{kind: "block", names: bindingNames(decl.Target)})
+			}
+		case *ast.SwitchStatement:
+			var names []string
+			for _, c := range n.Body {
+				names = append(names, lexicalNames(c.Consequent, true)...)
+			}
+			add(n, lexicalScope{kind: "block", names: names})
+		}
+		return true
+	})
+	return scopes
+}
+
+// scopeChain returns the scopes enclosing idx, innermost first.
+func scopeChain(prg *ast.Program, idx file.Idx) []lexicalScope {
+	var chain []lexicalScope
+	scopes := declaredScopes(prg)
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if i == 0 || (idx >= scopes[i].start && idx < scopes[i].end) {
+			chain = append(chain, scopes[i].lexicalScope)
+		}
+	}
+	return chain
+}
+
+// referencedNames returns the identifiers code refers to, and whether it uses
+// this outside of the functions it defines.
+func referencedNames(node ast.Node) (names []string, usesThis bool) {
+	walkAST(node, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Identifier:
+			names = append(names, n.Name.String())
+		case *ast.PropertyShort:
+			names = append(names, n.Name.Name.String())
+		case *ast.ThisExpression:
+			usesThis = true
+		case *ast.FunctionLiteral:
+			// A function has a this of its own, but sees the same names
+			params, _ := referencedNames(n.ParameterList)
+			body, _ := referencedNames(n.Body)
+			names = append(names, append(params, body...)...)
+			return false
+		}
+		return true
+	})
+	return uniqueNames(names), usesThis
+}
+
+// enclosingParameters returns the parameter list of the innermost function
+// around idx, nil at the top level.
+func enclosingParameters(prg *ast.Program, idx file.Idx) *ast.ParameterList {
+	var params *ast.ParameterList
+	walkAST(prg, func(node ast.Node) bool {
+		if node != ast.Node(prg) && (idx < node.Idx0() || idx >= node.Idx1()) {
+			return false
+		}
+		switch n := node.(type) {
+		case *ast.FunctionLiteral:
+			params = n.ParameterList
+		case *ast.ArrowFunctionLiteral:
+			params = n.ParameterList
+		}
+		return true
+	})
+	return params
+}
+
+// functionScope collects the parameters, var declarations and top-level
+// declarations of a function body.
+func functionScope(name string, params *ast.ParameterList, body ast.ConciseBody) lexicalScope {
+	scope := lexicalScope{kind: "function", name: name}
+	if params != nil {
+		scope.names = declarationNames(params.List)
+		if params.Rest != nil {
+			scope.names = append(scope.names, bindingNames(params.Rest)...)
+		}
+	}
+
+	block, ok := body.(*ast.BlockStatement)
+	if !ok {
+		return scope
+	}
+	walkAST(block, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionLiteral, *ast.ArrowFunctionLiteral, *ast.ClassLiteral:
+			return false
+		case *ast.VariableStatement:
+			scope.names = append(scope.names, declarationNames(n.List)...)
+		case *ast.ForLoopInitializerVarDeclList:
+			scope.names = append(scope.names, declarationNames(n.List)...)
+		case *ast.ForIntoVar:
+			if n.Binding != nil {
+				scope.names = append(scope.names, bindingNames(n.Binding.Target)...)
+			}
+		}
+		return true
+	})
+	scope.names = append(scope.names, lexicalNames(block.List, true)...)
+	return scope
+}
+
+// lexicalNames returns the let, const and class names declared directly in a
+// statement list, and function declarations when they are block scoped.
+func lexicalNames(list []ast.Statement, functions bool) []string {
+	var names []string
+	for _, stmt := range list {
+		switch s := stmt.(type) {
+		case *ast.LexicalDeclaration:
+			names = append(names, declarationNames(s.List)...)
+		case *ast.ClassDeclaration:
+			if s.Class != nil && s.Class.Name != nil {
+				names = append(names, s.Class.Name.Name.String())
+			}
+		case *ast.FunctionDeclaration:
+			if functions && s.Function != nil && s.Function.Name != nil {
+				names = append(names, s.Function.Name.Name.String())
+			}
+		}
+	}
+	return names
+}
+
+func declarationNames(list []*ast.Binding) []string {
+	var names []string
+	for _, b := range list {
+		if b != nil {
+			names = append(names, bindingNames(b.Target)...)
+		}
+	}
+	return names
+}
+
+// bindingNames returns the names bound by a declaration target, which can be
+// a plain identifier or a destructuring pattern.
+func bindingNames(target ast.Node) []string {
+	var names []string
+	switch t := target.(type) {
+	case *ast.Identifier:
+		names = append(names, t.Name.String())
+	case *ast.ObjectPattern:
+		for _, prop := range t.Properties {
+			switch p := prop.(type) {
+			case *ast.PropertyShort:
+				names = append(names, p.Name.Name.String())
+			case *ast.PropertyKeyed:
+				names = append(names, bindingNames(p.Value)...)
+			}
+		}
+		if t.Rest != nil {
+			names = append(names, bindingNames(t.Rest)...)
+		}
+	case *ast.ArrayPattern:
+		for _, elem := range t.Elements {
+			if elem != nil {
+				names = append(names, bindingNames(elem)...)
+			}
+		}
+		if t.Rest != nil {
+			names = append(names, bindingNames(t.Rest)...)
+		}
+	case *ast.AssignExpression:
+		// Pattern element with a default value
+		names = append(names, bindingNames(t.Left)...)
+	}
+	return names
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+)
+
+func (da *DebugAdapter) handleBreakpointLocations(req *Request) {
+	var args BreakpointLocationsArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	endLine := args.EndLine
+	if endLine < args.Line {
+		endLine = args.Line
+	}
+
+	positions := da.sourcePositions(args.Source)
+	if positions == nil {
+		// Not loaded yet, show where breakpoints will go once it is
+		filename := da.sourceFilename(args.Source)
+		if content, err := os.ReadFile(filename); err == nil {
+			positions, _ = compiledPositions(filename, string(content))
+		}
+	}
+
+	locations := []BreakpointLocation{}
+	for _, pos := range positions {
+		if pos.Line < args.Line || pos.Line > endLine {
+			continue
+		}
+		if pos.Line == args.Line && args.Column > 0 && pos.Column < args.Column {
+			continue
+		}
+		if pos.Line == endLine && args.EndColumn > 0 && pos.Column > args.EndColumn {
+			continue
+		}
+		locations = append(locations, BreakpointLocation{
+			Line:   pos.Line,
+			Column: pos.Column,
+		})
+	}
+
+	log.Printf("BreakpointLocations for %s lines %d-%d: %d locations",
+		args.Source.Path, args.Line, endLine, len(locations))
+
+	da.sendResponse(req.Seq, req.Command, true, BreakpointLocationsResponseBody{
+		Breakpoints: locations,
+	})
+}
+
+// sourceFilename returns the name the runtime knows a client source by.
+func (da *DebugAdapter) sourceFilename(source Source) string {
+	// Sources without a file are identified by their reference
+	if script := da.scriptByRef(source.SourceReference); script != nil {
+		return script.key()
+	}
+
+	filename := source.Path
+	if filename == "" {
+		filename = source.Name
+	}
+
+	// Make sure we're using the same filename as the program
+	if da.program != "" && filepath.Base(filename) == filepath.Base(da.program) {
+		filename = da.program
+	}
+	return filename
+}
+
+// sourcePositions returns the positions goja maps instructions to in a
+// client source, or nil if the runtime hasn't loaded it.
+func (da *DebugAdapter) sourcePositions(source Source) []file.Position {
+	script := da.scriptByRef(source.SourceReference)
+	if script == nil {
+		script = da.scriptByName(da.sourceFilename(source))
+	}
+	if script == nil {
+		return nil
+	}
+
+	da.scriptsMutex.Lock()
+	positions, name, text := script.positions, script.name, script.text
+	da.scriptsMutex.Unlock()
+	if positions != nil {
+		return positions
+	}
+
+	positions, err := compiledPositions(name, text)
+	if err != nil {
+		log.Printf("Failed to compile %s: %v", name, err)
+		return nil
+	}
+
+	da.scriptsMutex.Lock()
+	if script.text == text {
+		script.positions = positions
+	}
+	da.scriptsMutex.Unlock()
+	return positions
+}
+
+// compiledPositions compiles a script the same way RunScript does and
+// returns, in source order, every position the compiled code maps an
+// instruction to, including the ones inside nested functions. These are the
+// only places execution can pause at.
+func compiledPositions(name, text string) ([]file.Position, error) {
+	prg, err := goja.Compile(name, text, false)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]bool)
+	collectSourceOffsets(reflect.ValueOf(prg), offsets)
+
+	sorted := make([]int, 0, len(offsets))
+	for offset := range offsets {
+		if offset >= 0 && offset < len(text) {
+			sorted = append(sorted, offset)
+		}
+	}
+	sort.Ints(sorted)
+
+	src := file.NewFile(name, text, 1)
+	positions := []file.Position{}
+	for _, offset := range sorted {
+		pos := src.Position(offset)
+		if n := len(positions); n > 0 && positions[n-1].Line == pos.Line && positions[n-1].Column == pos.Column {
+			continue
+		}
+		positions = append(positions, pos)
+	}
+	return positions, nil
+}
+
+var programType = reflect.TypeOf((*goja.Program)(nil))
+
+// collectSourceOffsets adds the source offsets in a compiled program's
+// source map to offsets, then does the same for the programs of the
+// functions and classes it creates. goja doesn't export the source map, so it
+// is read with reflection (see GOJA_DEBUG_PROPOSAL.md).
+func collectSourceOffsets(prg reflect.Value, offsets map[int]bool) {
+	if prg.Type() != programType || prg.IsNil() {
+		return
+	}
+	p := prg.Elem()
+
+	srcMap := p.FieldByName("srcMap")
+	if srcMap.Kind() == reflect.Slice {
+		for i := 0; i < srcMap.Len(); i++ {
+			if srcPos := srcMap.Index(i).FieldByName("srcPos"); srcPos.Kind() == reflect.Int {
+				offsets[int(srcPos.Int())] = true
+			}
+		}
+	}
+
+	code := p.FieldByName("code")
+	if code.Kind() != reflect.Slice {
+		return
+	}
+	for i := 0; i < code.Len(); i++ {
+		collectInstructionPrograms(code.Index(i), offsets)
+	}
+}
+
+// collectInstructionPrograms looks for nested programs in an instruction,
+// like the body of the function a newFunc instruction creates.
+func collectInstructionPrograms(v reflect.Value, offsets map[int]bool) {
+	for v.Kind() == reflect.Interface || (v.Kind() == reflect.Ptr && v.Type() != programType) {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Type() == programType:
+			collectSourceOffsets(field, offsets)
+		case v.Type().Field(i).Anonymous:
+			// e.g. newArrowFunc embeds newFunc
+			collectInstructionPrograms(field, offsets)
+		}
+	}
+}
+
+// resolvePosition returns the first position at or after line and column.
+// Column 0 means the start of the line.
+func resolvePosition(positions []file.Position, line, column int) (file.Position, bool) {
+	for _, pos := range positions {
+		if pos.Line > line || (pos.Line == line && pos.Column >= column) {
+			return pos, true
+		}
+	}
+	return file.Position{}, false
+}
+
+// snapLimit returns how far a breakpoint requested at line and column may be
+// moved: to the end of the innermost statement, block or function around it.
+// ok is false at the top level of the script or without its AST, where any
+// later code will do.
+func snapLimit(prg *ast.Program, line, column int) (end file.Position, ok bool) {
+	if prg == nil {
+		return file.Position{}, false
+	}
+	if column < 1 {
+		column = 1
+	}
+	idx := positionIdx(prg, line, column)
+
+	var limit ast.Node
+	walkAST(prg, func(node ast.Node) bool {
+		if node == ast.Node(prg) {
+			return true
+		}
+		if idx < node.Idx0() || idx >= node.Idx1() {
+			return false
+		}
+		switch node.(type) {
+		case ast.Statement, *ast.FunctionLiteral, *ast.ArrowFunctionLiteral, *ast.ClassLiteral:
+			limit = node
+		}
+		return true
+	})
+	if limit == nil {
+		return file.Position{}, false
+	}
+	return nodePosition(prg, limit.Idx1()), true
+}
@@ -0,0 +1,64 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// breakpointLocations returns the lines of the locations reported for args,
+// once per line, and the columns on the first line asked for.
+func (s *testSession) breakpointLocations(args BreakpointLocationsArguments) (lines, columns []int) {
+	s.t.Helper()
+
+	var body BreakpointLocationsResponseBody
+	s.request("breakpointLocations", args, &body)
+	lines, columns = []int{}, []int{}
+	for _, loc := range body.Breakpoints {
+		if n := len(lines); n == 0 || lines[n-1] != loc.Line {
+			lines = append(lines, loc.Line)
+		}
+		if loc.Line == args.Line {
+			columns = append(columns, loc.Column)
+		}
+	}
+	return lines, columns
+}
+
+const locationsProgram = `var a = 1;
+
+function f(x) {
+  return x + a;
+}
+f(a); f(2);
+`
+
+func TestBreakpointLocations(t *testing.T) {
+	s := newTestSession(t, locationsProgram)
+
+	// Nothing is compiled for the blank line and the closing brace
+	lines, _ := s.breakpointLocations(BreakpointLocationsArguments{Source: Source{Path: s.program}, Line: 1, EndLine: 7})
+	if want := []int{1, 3, 4, 6}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines with locations = %v, want %v", lines, want)
+	}
+	_, columns := s.breakpointLocations(BreakpointLocationsArguments{Source: Source{Path: s.program}, Line: 6, Column: 7})
+	if want := []int{7, 8}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("locations on line 6 from column 7 = %v, want %v", columns, want)
+	}
+
+	// A file the program hasn't loaded is compiled from disk
+	other := filepath.Join(filepath.Dir(s.program), "other.js")
+	if err := os.WriteFile(other, []byte(locationsProgram), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines, _ = s.breakpointLocations(BreakpointLocationsArguments{Source: Source{Path: other}, Line: 2, EndLine: 5})
+	if want := []int{3, 4}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines with locations in other.js = %v, want %v", lines, want)
+	}
+
+	s.request("configurationDone", nil, nil)
+	s.terminated()
+}
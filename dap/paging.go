@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dop251/goja"
+)
+
+// chunkSize is the most elements returned for an array at once. Clients
+// that don't page get longer arrays split into [0..99] style ranges.
+const chunkSize = 100
+
+// arrayChunk is a range of array elements shown as a node of its own.
+type arrayChunk struct {
+	obj   *goja.Object
+	start int
+	count int
+}
+
+// isArray reports whether obj is a JS array, without exporting it.
+func isArray(obj *goja.Object) bool {
+	return obj.ClassName() == "Array"
+}
+
+func arrayLength(obj *goja.Object) int {
+	return int(obj.Get("length").ToInteger())
+}
+
+// childCounts returns the number of indexed and named children of an
+// expandable value, so the client can page through them. The named children
+// of an array are length and its properties that aren't elements, but goja
+// only lists those together with every index: only length is counted, the
+// others are found when the client asks for the named children.
+func childCounts(obj *goja.Object) (indexed, named int) {
+	if isArray(obj) {
+		return arrayLength(obj), 1
+	}
+	return 0, len(obj.Keys())
+}
+
+// objectVariables returns the children of an expanded object for a
+// variables request. Only the children the client asks for are read.
+func (da *DebugAdapter) objectVariables(obj *goja.Object, args VariablesArguments) []Variable {
+	if !isArray(obj) {
+		if args.Filter == "indexed" {
+			return []Variable{}
+		}
+		return da.propertyVariables(obj, pageItems(obj.Keys(), args.Start, args.Count))
+	}
+
+	length := arrayLength(obj)
+	switch {
+	case args.Filter == "named":
+		return pageItems(da.arrayProperties(obj, length), args.Start, args.Count)
+	case args.Filter == "indexed" || args.Count > 0:
+		return da.arrayElements(obj, args.Start, args.Count, length)
+	}
+
+	// The client doesn't page, send everything with long arrays in ranges
+	return append(da.arrayProperties(obj, length), da.arrayRange(obj, 0, length)...)
+}
+
+// propertyVariables returns the variables of the given properties of obj.
+func (da *DebugAdapter) propertyVariables(obj *goja.Object, keys []string) []Variable {
+	variables := []Variable{}
+	for _, key := range keys {
+		if val := obj.Get(key); val != nil {
+			variables = append(variables, da.valueToVariable(key, val))
+		}
+	}
+	return variables
+}
+
+// chunkVariables returns the children of a range of array elements.
+func (da *DebugAdapter) chunkVariables(chunk *arrayChunk, args VariablesArguments) []Variable {
+	if args.Filter == "named" {
+		return []Variable{}
+	}
+	if args.Filter == "indexed" || args.Count > 0 {
+		count := chunk.count - args.Start
+		if args.Count > 0 && args.Count < count {
+			count = args.Count
+		}
+		return da.arrayElements(chunk.obj, chunk.start+args.Start, count, chunk.start+chunk.count)
+	}
+	return da.arrayRange(chunk.obj, chunk.start, chunk.count)
+}
+
+// arrayProperties returns length and the own properties of an array that
+// aren't elements.
+func (da *DebugAdapter) arrayProperties(obj *goja.Object, length int) []Variable {
+	variables := []Variable{{
+		Name:  "length",
+		Value: strconv.Itoa(length),
+		Type:  "number",
+	}}
+	return append(variables, da.propertyVariables(obj, arrayNamedKeys(obj))...)
+}
+
+// arrayNamedKeys returns the own enumerable keys of an array that aren't
+// indices. goja can only list them together with every index, but their
+// values aren't read.
+func arrayNamedKeys(obj *goja.Object) []string {
+	var keys []string
+	for _, key := range obj.Keys() {
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && strconv.Itoa(i) == key {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// arrayElements returns count elements from start, stopping at end. A count
+// of 0 means up to end.
+func (da *DebugAdapter) arrayElements(obj *goja.Object, start, count, end int) []Variable {
+	if start < 0 {
+		start = 0
+	}
+	if count > 0 && start+count < end {
+		end = start + count
+	}
+
+	variables := []Variable{}
+	for i := start; i < end; i++ {
+		name := strconv.Itoa(i)
+		variables = append(variables, da.valueToVariable(name, obj.Get(name)))
+	}
+	return variables
+}
+
+// arrayRange lists count elements from start, or when there are more than
+// chunkSize, the ranges they are split into. Ranges hold up to chunkSize
+// elements or chunkSize smaller ranges.
+func (da *DebugAdapter) arrayRange(obj *goja.Object, start, count int) []Variable {
+	if count <= chunkSize {
+		return da.arrayElements(obj, start, count, start+count)
+	}
+
+	size := chunkSize
+	for count > size*chunkSize {
+		size *= chunkSize
+	}
+
+	variables := []Variable{}
+	for i := start; i < start+count; i += size {
+		n := size
+		if i+n > start+count {
+			n = start + count - i
+		}
+		da.varRefCounter++
+		da.varRefMap[da.varRefCounter] = &arrayChunk{obj: obj, start: i, count: n}
+		variables = append(variables, Variable{
+			Name:               fmt.Sprintf("[%d..%d]", i, i+n-1),
+			Value:              fmt.Sprintf("Array[%d]", n),
+			Type:               "array",
+			VariablesReference: da.varRefCounter,
+		})
+	}
+	return variables
+}
+
+// pageItems returns the count items from start, all the ones from start
+// when count is 0.
+func pageItems[T any](items []T, start, count int) []T {
+	if start <= 0 && count <= 0 {
+		return items
+	}
+	if start >= len(items) {
+		return []T{}
+	}
+	if start < 0 {
+		start = 0
+	}
+	items = items[start:]
+	if count > 0 && count < len(items) {
+		items = items[:count]
+	}
+	return items
+}
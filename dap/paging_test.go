@@ -0,0 +1,90 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const pagingProgram = `var list = [];
+for (var i = 0; i < 250; i++) list.push(i);
+list.extra = "x";
+var obj = {};
+for (var i = 0; i < 10; i++) obj["k" + i] = i;
+console.log("paused");
+`
+
+// variables returns the names of the children a variables request selects.
+func (s *testSession) variables(args VariablesArguments) []string {
+	s.t.Helper()
+
+	var body VariablesResponseBody
+	s.request("variables", args, &body)
+	names := []string{}
+	for _, v := range body.Variables {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+func TestArrayPaging(t *testing.T) {
+	s := newTestSession(t, pagingProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 6})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	var list EvaluateResponseBody
+	s.request("evaluate", EvaluateArguments{Expression: "list", FrameID: 1, Context: "watch"}, &list)
+	// Only length is counted without listing the elements, extra turns up
+	// among the named children
+	if list.IndexedVariables != 250 || list.NamedVariables != 1 {
+		t.Errorf("list has %d indexed and %d named children, want 250 and 1", list.IndexedVariables, list.NamedVariables)
+	}
+
+	ref := list.VariablesReference
+	tests := []struct {
+		name string
+		args VariablesArguments
+		want []string
+	}{
+		{"last page", VariablesArguments{Filter: "indexed", Start: 240, Count: 20},
+			[]string{"240", "241", "242", "243", "244", "245", "246", "247", "248", "249"}},
+		{"named", VariablesArguments{Filter: "named"}, []string{"length", "extra"}},
+		{"unpaged", VariablesArguments{}, []string{"length", "extra", "[0..99]", "[100..199]", "[200..249]"}},
+	}
+	for _, tt := range tests {
+		tt.args.VariablesReference = ref
+		if got := s.variables(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	s.resume()
+	s.terminated()
+}
+
+func TestObjectPaging(t *testing.T) {
+	s := newTestSession(t, pagingProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 6})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	var obj EvaluateResponseBody
+	s.request("evaluate", EvaluateArguments{Expression: "obj", FrameID: 1, Context: "watch"}, &obj)
+	if obj.IndexedVariables != 0 || obj.NamedVariables != 10 {
+		t.Errorf("obj has %d indexed and %d named children, want 0 and 10", obj.IndexedVariables, obj.NamedVariables)
+	}
+
+	ref := obj.VariablesReference
+	got := s.variables(VariablesArguments{VariablesReference: ref, Filter: "named", Start: 3, Count: 4})
+	if want := []string{"k3", "k4", "k5", "k6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("named page = %v, want %v", got, want)
+	}
+	if got := s.variables(VariablesArguments{VariablesReference: ref, Filter: "indexed"}); len(got) != 0 {
+		t.Errorf("indexed children of a plain object = %v, want none", got)
+	}
+
+	s.resume()
+	s.terminated()
+}
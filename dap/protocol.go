@@ -34,8 +34,8 @@ type InitializeRequestArguments struct {
 	ClientName                   string `json:"clientName,omitempty"`
 	AdapterID                    string `json:"adapterID"`
 	Locale                       string `json:"locale,omitempty"`
-	LinesStartAt1                bool   `json:"linesStartAt1"`
-	ColumnsStartAt1              bool   `json:"columnsStartAt1"`
+	LinesStartAt1                *bool  `json:"linesStartAt1,omitempty"`   // true when omitted
+	ColumnsStartAt1              *bool  `json:"columnsStartAt1,omitempty"` // true when omitted
 	PathFormat                   string `json:"pathFormat,omitempty"`
 	SupportsVariableType         bool   `json:"supportsVariableType,omitempty"`
 	SupportsVariablePaging       bool   `json:"supportsVariablePaging,omitempty"`
@@ -43,42 +43,71 @@ type InitializeRequestArguments struct {
 }
 
 type Capabilities struct {
-	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
-	SupportsFunctionBreakpoints      bool `json:"supportsFunctionBreakpoints"`
-	SupportsConditionalBreakpoints   bool `json:"supportsConditionalBreakpoints"`
-	SupportsEvaluateForHovers        bool `json:"supportsEvaluateForHovers"`
-	SupportsStepBack                 bool `json:"supportsStepBack"`
-	SupportsSetVariable              bool `json:"supportsSetVariable"`
-	SupportsRestartFrame             bool `json:"supportsRestartFrame"`
-	SupportsStepInTargetsRequest     bool `json:"supportsStepInTargetsRequest"`
-	SupportsDelayedStackTraceLoading bool `json:"supportsDelayedStackTraceLoading"`
-	SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+	SupportsConfigurationDoneRequest   bool                         `json:"supportsConfigurationDoneRequest"`
+	SupportsFunctionBreakpoints        bool                         `json:"supportsFunctionBreakpoints"`
+	SupportsConditionalBreakpoints     bool                         `json:"supportsConditionalBreakpoints"`
+	SupportsHitConditionalBreakpoints  bool                         `json:"supportsHitConditionalBreakpoints"`
+	SupportsLogPoints                  bool                         `json:"supportsLogPoints"`
+	SupportsEvaluateForHovers          bool                         `json:"supportsEvaluateForHovers"`
+	SupportsStepBack                   bool                         `json:"supportsStepBack"`
+	SupportsSetVariable                bool                         `json:"supportsSetVariable"`
+	SupportsSetExpression              bool                         `json:"supportsSetExpression"`
+	SupportsRestartFrame               bool                         `json:"supportsRestartFrame"`
+	SupportsStepInTargetsRequest       bool                         `json:"supportsStepInTargetsRequest"`
+	SupportsGotoTargetsRequest         bool                         `json:"supportsGotoTargetsRequest"`
+	SupportsDelayedStackTraceLoading   bool                         `json:"supportsDelayedStackTraceLoading"`
+	SupportsTerminateRequest           bool                         `json:"supportsTerminateRequest"`
+	SupportsExceptionInfoRequest       bool                         `json:"supportsExceptionInfoRequest"`
+	SupportsCompletionsRequest         bool                         `json:"supportsCompletionsRequest"`
+	SupportsDataBreakpoints            bool                         `json:"supportsDataBreakpoints"`
+	SupportsLoadedSourcesRequest       bool                         `json:"supportsLoadedSourcesRequest"`
+	SupportsBreakpointLocationsRequest bool                         `json:"supportsBreakpointLocationsRequest"`
+	SupportsRestartRequest             bool                         `json:"supportsRestartRequest"`
+	ExceptionBreakpointFilters         []ExceptionBreakpointsFilter `json:"exceptionBreakpointFilters,omitempty"`
+}
+
+type ExceptionBreakpointsFilter struct {
+	Filter      string `json:"filter"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+	Default     bool   `json:"default,omitempty"`
 }
 
 // Launch request
 type LaunchRequestArguments struct {
-	NoDebug     bool     `json:"noDebug,omitempty"`
-	Program     string   `json:"program"`
-	Args        []string `json:"args,omitempty"`
-	StopOnEntry bool     `json:"stopOnEntry,omitempty"`
+	NoDebug      bool     `json:"noDebug,omitempty"`
+	Program      string   `json:"program"`
+	Args         []string `json:"args,omitempty"`
+	StopOnEntry  bool     `json:"stopOnEntry,omitempty"`
+	AllowGetters bool     `json:"allowGetters,omitempty"` // hovers and watches may run getters
+}
+
+type RestartArguments struct {
+	Arguments *LaunchRequestArguments `json:"arguments,omitempty"`
 }
 
 // Breakpoint types
 type Source struct {
-	Name string `json:"name,omitempty"`
-	Path string `json:"path,omitempty"`
+	Name             string `json:"name,omitempty"`
+	Path             string `json:"path,omitempty"`
+	SourceReference  int    `json:"sourceReference,omitempty"`
+	Origin           string `json:"origin,omitempty"`
+	PresentationHint string `json:"presentationHint,omitempty"`
 }
 
 type SourceBreakpoint struct {
-	Line      int    `json:"line"`
-	Column    int    `json:"column,omitempty"`
-	Condition string `json:"condition,omitempty"`
+	Line         int    `json:"line"`
+	Column       int    `json:"column,omitempty"`
+	Condition    string `json:"condition,omitempty"`
+	HitCondition string `json:"hitCondition,omitempty"`
+	LogMessage   string `json:"logMessage,omitempty"`
 }
 
 type Breakpoint struct {
 	ID        int    `json:"id"`
 	Verified  bool   `json:"verified"`
 	Message   string `json:"message,omitempty"`
+	Reason    string `json:"reason,omitempty"` // "pending" or "failed" when not verified
 	Source    Source `json:"source,omitempty"`
 	Line      int    `json:"line,omitempty"`
 	Column    int    `json:"column,omitempty"`
@@ -86,6 +115,20 @@ type Breakpoint struct {
 	EndColumn int    `json:"endColumn,omitempty"`
 }
 
+type FunctionBreakpoint struct {
+	Name         string `json:"name"`
+	Condition    string `json:"condition,omitempty"`
+	HitCondition string `json:"hitCondition,omitempty"`
+}
+
+type SetFunctionBreakpointsArguments struct {
+	Breakpoints []FunctionBreakpoint `json:"breakpoints"`
+}
+
+type SetExceptionBreakpointsArguments struct {
+	Filters []string `json:"filters"`
+}
+
 type SetBreakpointsArguments struct {
 	Source      Source             `json:"source"`
 	Breakpoints []SourceBreakpoint `json:"breakpoints,omitempty"`
@@ -95,6 +138,25 @@ type SetBreakpointsResponseBody struct {
 	Breakpoints []Breakpoint `json:"breakpoints"`
 }
 
+type BreakpointLocationsArguments struct {
+	Source    Source `json:"source"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+}
+
+type BreakpointLocation struct {
+	Line      int `json:"line"`
+	Column    int `json:"column,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	EndColumn int `json:"endColumn,omitempty"`
+}
+
+type BreakpointLocationsResponseBody struct {
+	Breakpoints []BreakpointLocation `json:"breakpoints"`
+}
+
 // Thread types
 type Thread struct {
 	ID   int    `json:"id"`
@@ -155,9 +217,10 @@ type Variable struct {
 }
 
 type VariablesArguments struct {
-	VariablesReference int `json:"variablesReference"`
-	Start              int `json:"start,omitempty"`
-	Count              int `json:"count,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	Filter             string `json:"filter,omitempty"` // "indexed" or "named"
+	Start              int    `json:"start,omitempty"`
+	Count              int    `json:"count,omitempty"`
 }
 
 type VariablesResponseBody struct {
@@ -175,6 +238,111 @@ type EvaluateResponseBody struct {
 	Result             string `json:"result"`
 	Type               string `json:"type,omitempty"`
 	VariablesReference int    `json:"variablesReference"`
+	NamedVariables     int    `json:"namedVariables,omitempty"`
+	IndexedVariables   int    `json:"indexedVariables,omitempty"`
+}
+
+// Set variable types
+type SetVariableArguments struct {
+	VariablesReference int    `json:"variablesReference"`
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+}
+
+type SetVariableResponseBody struct {
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	NamedVariables     int    `json:"namedVariables,omitempty"`
+	IndexedVariables   int    `json:"indexedVariables,omitempty"`
+}
+
+type SetExpressionArguments struct {
+	Expression string `json:"expression"`
+	Value      string `json:"value"`
+	FrameID    int    `json:"frameId,omitempty"`
+}
+
+type SetExpressionResponseBody struct {
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	NamedVariables     int    `json:"namedVariables,omitempty"`
+	IndexedVariables   int    `json:"indexedVariables,omitempty"`
+}
+
+// Goto types
+type GotoTargetsArguments struct {
+	Source Source `json:"source"`
+	Line   int    `json:"line"`
+	Column int    `json:"column,omitempty"`
+}
+
+type GotoArguments struct {
+	ThreadID int `json:"threadId"`
+	TargetID int `json:"targetId"`
+}
+
+// Data breakpoint types
+type DataBreakpointInfoArguments struct {
+	VariablesReference int    `json:"variablesReference,omitempty"`
+	Name               string `json:"name"`
+	FrameID            int    `json:"frameId,omitempty"`
+}
+
+type DataBreakpointInfoResponseBody struct {
+	DataID      *string  `json:"dataId"`
+	Description string   `json:"description"`
+	AccessTypes []string `json:"accessTypes,omitempty"`
+	CanPersist  bool     `json:"canPersist,omitempty"`
+}
+
+type DataBreakpoint struct {
+	DataID       string `json:"dataId"`
+	AccessType   string `json:"accessType,omitempty"`
+	Condition    string `json:"condition,omitempty"`
+	HitCondition string `json:"hitCondition,omitempty"`
+}
+
+type SetDataBreakpointsArguments struct {
+	Breakpoints []DataBreakpoint `json:"breakpoints"`
+}
+
+// Source types
+type SourceArguments struct {
+	Source          *Source `json:"source,omitempty"`
+	SourceReference int     `json:"sourceReference"`
+}
+
+type SourceResponseBody struct {
+	Content  string `json:"content"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+type LoadedSourcesResponseBody struct {
+	Sources []Source `json:"sources"`
+}
+
+// Completions types
+type CompletionsArguments struct {
+	FrameID int    `json:"frameId,omitempty"`
+	Text    string `json:"text"`
+	Column  int    `json:"column"`
+	Line    int    `json:"line,omitempty"`
+}
+
+type CompletionItem struct {
+	Label string `json:"label"`
+	Type  string `json:"type,omitempty"`
+}
+
+type CompletionsResponseBody struct {
+	Targets []CompletionItem `json:"targets"`
+}
+
+// Restart frame types
+type RestartFrameArguments struct {
+	FrameID int `json:"frameId"`
 }
 
 // Continue/Step types
@@ -192,12 +360,48 @@ type NextArguments struct {
 
 type StepInArguments struct {
 	ThreadID int `json:"threadId"`
+	TargetID int `json:"targetId,omitempty"`
+}
+
+type StepInTargetsArguments struct {
+	FrameID int `json:"frameId"`
+}
+
+type StepInTarget struct {
+	ID        int    `json:"id"`
+	Label     string `json:"label"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+}
+
+type StepInTargetsResponseBody struct {
+	Targets []StepInTarget `json:"targets"`
 }
 
 type StepOutArguments struct {
 	ThreadID int `json:"threadId"`
 }
 
+// Exception types
+type ExceptionInfoArguments struct {
+	ThreadID int `json:"threadId"`
+}
+
+type ExceptionInfoResponseBody struct {
+	ExceptionID string            `json:"exceptionId"`
+	Description string            `json:"description,omitempty"`
+	BreakMode   string            `json:"breakMode"` // "never", "always", "unhandled", "userUnhandled"
+	Details     *ExceptionDetails `json:"details,omitempty"`
+}
+
+type ExceptionDetails struct {
+	Message    string `json:"message,omitempty"`
+	TypeName   string `json:"typeName,omitempty"`
+	StackTrace string `json:"stackTrace,omitempty"`
+}
+
 // Pause types
 type PauseArguments struct {
 	ThreadID int `json:"threadId"`
@@ -208,6 +412,7 @@ type StoppedEventBody struct {
 	Reason            string `json:"reason"`
 	Description       string `json:"description,omitempty"`
 	ThreadID          int    `json:"threadId,omitempty"`
+	Text              string `json:"text,omitempty"`
 	AllThreadsStopped bool   `json:"allThreadsStopped"`
 }
 
@@ -218,6 +423,16 @@ type TerminatedEventBody struct {
 	Restart interface{} `json:"restart,omitempty"`
 }
 
+type BreakpointEventBody struct {
+	Reason     string     `json:"reason"` // "changed", "new", "removed"
+	Breakpoint Breakpoint `json:"breakpoint"`
+}
+
+type LoadedSourceEventBody struct {
+	Reason string `json:"reason"`
+	Source Source `json:"source"`
+}
+
 type ExitedEventBody struct {
 	ExitCode int `json:"exitCode"`
 }
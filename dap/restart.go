@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+func (da *DebugAdapter) handleRestart(req *Request) {
+	args := RestartArguments{}
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	launchArgs := da.launchArgs
+	if args.Arguments != nil {
+		launchArgs = *args.Arguments
+	}
+
+	log.Printf("Restart request for %s", launchArgs.Program)
+
+	started := da.executionDone != nil
+	da.stopExecution()
+
+	if err := da.launch(launchArgs); err != nil {
+		da.sendErrorResponse(req, err)
+		return
+	}
+	da.sendResponse(req.Seq, req.Command, true, nil)
+
+	// Before configurationDone the client hasn't asked to run it yet
+	if started {
+		da.runProgram()
+	}
+}
+
+// runProgram runs the launched program in a goroutine of its own.
+func (da *DebugAdapter) runProgram() {
+	done := make(chan struct{})
+	da.executionDone = done
+
+	go func() {
+		defer close(done)
+		da.startExecution()
+	}()
+}
+
+// stopExecution interrupts the running program, resuming it first if it is
+// paused, and waits for its goroutine to end.
+func (da *DebugAdapter) stopExecution() {
+	done := da.executionDone
+	if done == nil {
+		return
+	}
+	da.executionDone = nil
+
+	da.restarting = true
+	defer func() { da.restarting = false }()
+	da.vm.Interrupt("restart")
+
+	for {
+		da.debugStateMutex.Lock()
+		da.nextCommand = debugContinue
+		if da.waitingForCmd {
+			da.waitingForCmd = false
+			close(da.commandReady)
+			da.commandReady = make(chan struct{})
+		}
+		da.debugStateMutex.Unlock()
+
+		// The handler may still be on its way to waiting for a command
+		select {
+		case <-done:
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// resetRunState forgets everything tied to the previous runtime. Source,
+// function and exception breakpoints are kept and applied again to the new
+// one.
+func (da *DebugAdapter) resetRunState() {
+	da.bpMutex.Lock()
+	da.throwSites = nil
+	da.lastPoints = nil
+
+	// The watched objects belonged to the old runtime
+	var lost []Breakpoint
+	for _, w := range da.dataWatches {
+		if w.active {
+			lost = append(lost, Breakpoint{
+				ID:      w.id,
+				Message: "The watched object is gone, the program was restarted",
+				Reason:  "failed",
+			})
+		}
+	}
+	da.dataWatches = make(map[string]*dataWatch)
+	da.dataObjects = nil
+	da.bpMutex.Unlock()
+
+	for _, bp := range lost {
+		da.sendEvent("breakpoint", BreakpointEventBody{
+			Reason:     "changed",
+			Breakpoint: bp,
+		})
+	}
+
+	da.dataHit = nil
+	da.exception = nil
+	da.uncaughtReported = false
+	da.stepTarget = nil
+	da.stepInCalls = nil
+	da.varRefMap = make(map[int]interface{})
+	da.safeEval = nil
+	da.forgetPausedStack()
+
+	da.removeScripts()
+}
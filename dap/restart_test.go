@@ -0,0 +1,40 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRestartKeepsBreakpoints(t *testing.T) {
+	s := newTestSession(t, `var runs = typeof runs === "number" ? runs + 1 : 1;
+function work() {
+  var x = runs;
+  return x;
+}
+work();
+`)
+	s.setBreakpoints(SourceBreakpoint{Line: 6})
+	s.request("setFunctionBreakpoints", SetFunctionBreakpointsArguments{
+		Breakpoints: []FunctionBreakpoint{{Name: "work"}},
+	}, nil)
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	// A fresh runtime, with the source and function breakpoints applied again
+	s.request("restart", nil, nil)
+	for _, want := range []struct {
+		reason string
+		line   int
+	}{{"breakpoint", 6}, {"function breakpoint", 3}} {
+		stop, frame := s.stopped()
+		if stop.Reason != want.reason || frame.Line != want.line {
+			t.Errorf("stopped for %q at line %d after the restart; want %s at line %d", stop.Reason, frame.Line, want.reason, want.line)
+		}
+		if got := s.evaluate("runs"); got != "1" {
+			t.Errorf("runs = %s, the previous run's globals were kept", got)
+		}
+		s.resume()
+	}
+	s.terminated()
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+)
+
+// frameScope is a level of a frame's scope chain other than the frame's own
+// function, shown as a scope of its own: a block, a catch clause, an
+// enclosing function or the script.
+type frameScope struct {
+	frameID  int
+	scope    lexicalScope
+	shadowed map[string]bool // names hidden by an inner scope
+}
+
+func (da *DebugAdapter) handleScopes(req *Request) {
+	var args ScopesArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	scopes := []Scope{}
+
+	// Exception scope while stopped on an exception
+	if da.exception != nil {
+		scopes = append(scopes, da.newScope("Exception", map[string]interface{}{
+			"type": "exception",
+		}))
+	}
+
+	chain := da.frameScopeChain(args.FrameID)
+	if chain == nil {
+		// Without the script's AST the chain is unknown
+		chain = []lexicalScope{{kind: "function"}}
+	}
+
+	seen := make(map[string]bool)
+	local := false
+	for _, level := range chain {
+		var name string
+		switch level.kind {
+		case "function":
+			if !local {
+				// The frame's own function, read by the adapter when the
+				// engine gives access to its frame
+				local = true
+				for _, n := range level.names {
+					seen[n] = true
+				}
+				if da.frames.canReadFrames() {
+					scopes = append(scopes, da.newScope("Local", map[string]interface{}{
+						"type":    "local",
+						"frameID": args.FrameID,
+					}))
+				}
+				continue
+			}
+			name = fmt.Sprintf("Closure (%s)", level.name)
+		case "block":
+			name = "Block"
+		case "catch":
+			name = "Catch"
+		case "script":
+			name = "Script"
+		}
+
+		level.names = uniqueNames(level.names)
+		if len(level.names) == 0 {
+			continue
+		}
+		shadowed := make(map[string]bool)
+		for _, n := range level.names {
+			if seen[n] {
+				shadowed[n] = true
+			}
+			seen[n] = true
+		}
+		if level.kind != "script" && !da.frames.canReadFrames() {
+			// Only script scope variables can be read from the global
+			// scope, the others live in frames the engine hides
+			continue
+		}
+		scopes = append(scopes, da.newScope(name, &frameScope{
+			frameID:  args.FrameID,
+			scope:    level,
+			shadowed: shadowed,
+		}))
+	}
+
+	scopes = append(scopes, da.newScope("Global", map[string]interface{}{
+		"type": "global",
+	}))
+
+	log.Printf("Scopes for frame %d: %d scopes", args.FrameID, len(scopes))
+
+	da.sendResponse(req.Seq, req.Command, true, ScopesResponseBody{
+		Scopes: scopes,
+	})
+}
+
+// newScope registers the container of a scope and describes it.
+func (da *DebugAdapter) newScope(name string, container interface{}) Scope {
+	da.varRefCounter++
+	da.varRefMap[da.varRefCounter] = container
+	return Scope{
+		Name:               name,
+		VariablesReference: da.varRefCounter,
+		Expensive:          false,
+	}
+}
+
+// frameScopeChain returns the scope chain at the position a frame is paused
+// at, innermost first, or nil if the frame's script wasn't parsed.
+func (da *DebugAdapter) frameScopeChain(frameID int) []lexicalScope {
+	prg, idx, ok := da.framePosition(frameID)
+	if !ok {
+		return nil
+	}
+	return scopeChain(prg, idx)
+}
+
+// frameParameters returns the parameters declared by a frame's function, ok
+// is false for top-level code or when the script wasn't parsed.
+func (da *DebugAdapter) frameParameters(frameID int) (params *ast.ParameterList, ok bool) {
+	prg, idx, ok := da.framePosition(frameID)
+	if !ok {
+		return nil, false
+	}
+	params = enclosingParameters(prg, idx)
+	return params, params != nil
+}
+
+// framePosition finds the parsed script of a frame and the place in it the
+// frame is paused at.
+func (da *DebugAdapter) framePosition(frameID int) (*ast.Program, file.Idx, bool) {
+	frame, ok := da.frameMap[frameID]
+	if !ok {
+		stack := da.pausedStack()
+		i := frameIndex(frameID)
+		if i >= len(stack) {
+			return nil, 0, false
+		}
+		frame = &stack[i]
+	}
+
+	pos := frame.Position()
+	da.scriptsMutex.Lock()
+	prg := da.scriptASTs[pos.Filename]
+	da.scriptsMutex.Unlock()
+	if prg == nil || pos.Line <= 0 {
+		return nil, 0, false
+	}
+	return prg, positionIdx(prg, pos.Line, pos.Column), true
+}
+
+// checkFrameEvaluation reports why code can't be evaluated in a frame. An
+// engine that can't read frame scopes evaluates against the global scope, so
+// code that refers to the frame's locals, closures or this would see other
+// values, or none.
+func (da *DebugAdapter) checkFrameEvaluation(src string, frameID int) error {
+	if frameIndex(frameID) >= len(da.pausedStack()) {
+		return fmt.Errorf("frame %d is not on the stack", frameID)
+	}
+	if da.frames.canReadFrames() {
+		return nil
+	}
+
+	prg, err := parser.ParseFile(nil, "", src, 0)
+	if err != nil {
+		// Evaluating it reports the syntax error
+		return nil
+	}
+	names, usesThis := referencedNames(prg)
+
+	// Without the frame's script, e.g. in eval code, any name could be local
+	chain := da.frameScopeChain(frameID)
+	if chain == nil && da.pausedStack()[frameIndex(frameID)].SrcName() != "<native>" && (len(names) > 0 || usesThis) {
+		return fmt.Errorf("the scopes of frame %d are unknown, this goja build can't read frame scopes", frameID)
+	}
+
+	local := make(map[string]bool)
+	inFunction := false
+	for _, level := range chain {
+		if level.kind == "script" {
+			continue
+		}
+		if level.kind == "function" {
+			inFunction = true
+			local["arguments"] = true
+		}
+		for _, name := range level.names {
+			local[name] = true
+		}
+	}
+
+	if usesThis && inFunction {
+		return fmt.Errorf("'this' of frame %d is not available in this goja build", frameID)
+	}
+	for _, name := range names {
+		if local[name] {
+			return fmt.Errorf("'%s' is local to frame %d, this goja build can't read frame scopes", name, frameID)
+		}
+	}
+	return nil
+}
+
+// scopeVariables reads the variables of a scope level. Names hidden by an
+// inner scope can't be reached by name from the frame, so their value isn't
+// shown.
+func (da *DebugAdapter) scopeVariables(fs *frameScope) []Variable {
+	variables := []Variable{}
+	for _, name := range fs.scope.names {
+		if fs.shadowed[name] {
+			variables = append(variables, Variable{
+				Name:  name,
+				Value: "(shadowed)",
+				Type:  "unknown",
+			})
+			continue
+		}
+
+		val, err := da.readScopeVariable(frameIndex(fs.frameID), &fs.scope, name)
+		if err != nil {
+			value := fmt.Sprintf("(%v)", err)
+			if strings.Contains(err.Error(), "before initialization") {
+				// let and const in their temporal dead zone
+				value = "(uninitialized)"
+			}
+			variables = append(variables, Variable{
+				Name:  name,
+				Value: value,
+				Type:  "unknown",
+			})
+			continue
+		}
+		variables = append(variables, da.valueToVariable(name, val))
+	}
+	return variables
+}
+
+// setScopeVariable assigns a variable of a scope level in its frame.
+func (da *DebugAdapter) setScopeVariable(fs *frameScope, name, value string) (goja.Value, error) {
+	if !isIdentifier(name) {
+		return nil, fmt.Errorf("cannot assign to '%s'", name)
+	}
+	if fs.shadowed[name] {
+		return nil, fmt.Errorf("cannot modify '%s': it is shadowed by an inner scope", name)
+	}
+	if fs.scope.kind == "script" {
+		return da.evaluateInFrame(fmt.Sprintf("%s = (%s)", name, value), frameIndex(fs.frameID))
+	}
+	return da.setLocalVariable(frameIndex(fs.frameID), name, value)
+}
+
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,142 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// scopes returns the variables references of a frame's scopes by name.
+func (s *testSession) scopes(frameID int) map[string]int {
+	s.t.Helper()
+
+	var body ScopesResponseBody
+	s.request("scopes", ScopesArguments{FrameID: frameID}, &body)
+	refs := make(map[string]int)
+	for _, scope := range body.Scopes {
+		refs[scope.Name] = scope.VariablesReference
+	}
+	return refs
+}
+
+// values returns the values of the variables in a container by name.
+func (s *testSession) values(ref int) map[string]string {
+	s.t.Helper()
+
+	var body VariablesResponseBody
+	s.request("variables", VariablesArguments{VariablesReference: ref}, &body)
+	values := make(map[string]string)
+	for _, v := range body.Variables {
+		values[v.Name] = v.Value
+	}
+	return values
+}
+
+const localsProgram = `var frames = {};
+function printMessage(message, ...rest) {
+  frames.printMessage = { locals: { message: message }, args: arguments };
+  console.log(message);
+}
+function sum(a) {
+  frames.sum = { locals: {}, args: arguments };
+  return a;
+}
+printMessage("hello", 1, 2);
+sum(1, 2, 3);
+`
+
+func TestLocalVariables(t *testing.T) {
+	s := newFramesTestSession(t, localsProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 4}, SourceBreakpoint{Line: 8})
+	s.request("configurationDone", nil, nil)
+
+	// Parameters by name, the rest parameter gets the remaining arguments
+	s.stopped()
+	local, ok := s.scopes(1)["Local"]
+	if !ok {
+		t.Fatal("printMessage has no Local scope")
+	}
+	want := map[string]string{"message": "hello", "rest": "Array[2]"}
+	if got := s.values(local); !reflect.DeepEqual(got, want) {
+		t.Errorf("locals of printMessage = %v, want %v", got, want)
+	}
+	s.resume()
+
+	// Arguments beyond the parameters are shown as arguments
+	s.stopped()
+	want = map[string]string{"a": "1", "arguments": "Array[3]"}
+	if got := s.values(s.scopes(1)["Local"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("locals of sum = %v, want %v", got, want)
+	}
+	s.resume()
+	s.terminated()
+}
+
+const scopeChainProgram = `var frames = {};
+let total = 10;
+function outer(base) {
+  var factor = 2;
+  return function inner(x) {
+    if (x > 0) {
+      let doubled = x * factor;
+      frames.inner = { locals: { x: x }, args: arguments, eval: (s) => eval(s) };
+      console.log(doubled + total);
+    }
+  };
+}
+outer(1)(5);
+`
+
+// scopeNames returns the names of a frame's scopes in order.
+func (s *testSession) scopeNames(frameID int) []string {
+	s.t.Helper()
+
+	var body ScopesResponseBody
+	s.request("scopes", ScopesArguments{FrameID: frameID}, &body)
+	names := []string{}
+	for _, scope := range body.Scopes {
+		names = append(names, scope.Name)
+	}
+	return names
+}
+
+func TestScopeChain(t *testing.T) {
+	s := newFramesTestSession(t, scopeChainProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 9})
+	s.request("configurationDone", nil, nil)
+
+	s.stopped()
+	want := []string{"Block", "Local", "Closure (outer)", "Script", "Global"}
+	if got := s.scopeNames(1); !reflect.DeepEqual(got, want) {
+		t.Fatalf("scopes = %v, want %v", got, want)
+	}
+	refs := s.scopes(1)
+	for scope, want := range map[string]map[string]string{
+		"Block":           {"doubled": "10"},
+		"Local":           {"x": "5"},
+		"Closure (outer)": {"base": "1", "factor": "2"},
+		"Script":          {"total": "10"},
+	} {
+		if got := s.values(refs[scope]); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s scope = %v, want %v", scope, got, want)
+		}
+	}
+	s.resume()
+	s.terminated()
+}
+
+func TestScopesWithoutFrameAccess(t *testing.T) {
+	skipWithFrameAccess(t)
+
+	s := newTestSession(t, scopeChainProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 9})
+	s.request("configurationDone", nil, nil)
+
+	s.stopped()
+	if got, want := s.scopeNames(1), []string{"Script", "Global"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scopes = %v, want %v: the engine can't read the others", got, want)
+	}
+	s.resume()
+	s.terminated()
+}
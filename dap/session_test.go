@@ -0,0 +1,300 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// The adapter logs every request and pause
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// skipWithFrameAccess skips a test of what the adapter does when the engine
+// can't read the scopes of paused frames.
+func skipWithFrameAccess(t *testing.T) {
+	if newFrameInspector().canReadFrames() {
+		t.Skip("this goja build can read frame scopes")
+	}
+}
+
+// testMessage is a response or event read from the adapter.
+type testMessage struct {
+	Type       string          `json:"type"`
+	Event      string          `json:"event"`
+	Command    string          `json:"command"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// testSession drives a DebugAdapter over pipes the way an editor does over
+// stdio.
+type testSession struct {
+	t        *testing.T
+	program  string
+	w        io.Writer
+	seq      int
+	messages chan testMessage
+	pending  []testMessage // events read while waiting for something else
+	outputs  []testOutput
+}
+
+// testOutput is the body of an output event.
+type testOutput struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
+
+// newTestSession starts an adapter and launches src as the program, without
+// running it yet: breakpoints can be set before configurationDone.
+func newTestSession(t *testing.T, src string) *testSession {
+	t.Helper()
+	return launchTestSession(t, src, LaunchRequestArguments{})
+}
+
+// launchTestSession is newTestSession with launch options. The program is
+// filled in.
+func launchTestSession(t *testing.T, src string, launch LaunchRequestArguments) *testSession {
+	t.Helper()
+	return startTestSession(t, src, sessionOptions{launch: launch})
+}
+
+// sessionOptions changes how startTestSession starts a session.
+type sessionOptions struct {
+	initialize InitializeRequestArguments // the adapter ID is filled in
+	launch     LaunchRequestArguments     // the program is filled in
+	setup      func(*DebugAdapter)        // called before the adapter runs, unless nil
+}
+
+// startTestSession is newTestSession with options.
+func startTestSession(t *testing.T, src string, opts sessionOptions) *testSession {
+	t.Helper()
+
+	program := filepath.Join(t.TempDir(), "main.js")
+	if err := os.WriteFile(program, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	da := NewDebugAdapter(inR, outW)
+	if opts.setup != nil {
+		opts.setup(da)
+	}
+	go da.Run()
+	t.Cleanup(func() {
+		inW.Close()
+		outR.Close()
+	})
+
+	s := &testSession{
+		t:        t,
+		program:  program,
+		w:        inW,
+		messages: make(chan testMessage, 1000),
+	}
+	go s.read(bufio.NewReader(outR))
+
+	opts.initialize.AdapterID = "goja"
+	s.request("initialize", opts.initialize, nil)
+	opts.launch.Program = program
+	s.request("launch", opts.launch, nil)
+	return s
+}
+
+// read decodes the messages the adapter writes until the pipe closes.
+func (s *testSession) read(r *bufio.Reader) {
+	defer close(s.messages)
+	for {
+		length := 0
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				break
+			}
+			if value, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+				length, _ = strconv.Atoi(strings.TrimSpace(value))
+			}
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return
+		}
+		var msg testMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		s.messages <- msg
+	}
+}
+
+// next returns the first message matching match. Events that don't match
+// are kept for later waits, output is collected.
+func (s *testSession) next(what string, match func(testMessage) bool) testMessage {
+	s.t.Helper()
+
+	for i, msg := range s.pending {
+		if match(msg) {
+			s.pending = append(s.pending[:i:i], s.pending[i+1:]...)
+			return msg
+		}
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-s.messages:
+			if !ok {
+				s.t.Fatalf("adapter closed the connection waiting for %s", what)
+			}
+			if msg.Type == "event" && msg.Event == "output" {
+				var body testOutput
+				json.Unmarshal(msg.Body, &body)
+				s.outputs = append(s.outputs, body)
+			}
+			if match(msg) {
+				return msg
+			}
+			if msg.Type == "event" {
+				s.pending = append(s.pending, msg)
+			}
+		case <-timeout:
+			s.t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// request sends a request, waits for its response and decodes its body into
+// body, unless body is nil. A failed request fails the test.
+func (s *testSession) request(command string, args interface{}, body interface{}) {
+	s.t.Helper()
+
+	if msg := s.send(command, args); !msg.Success {
+		s.t.Fatalf("%s failed: %s", command, msg.Message)
+	} else if body != nil {
+		if err := json.Unmarshal(msg.Body, body); err != nil {
+			s.t.Fatalf("%s: decoding %s: %v", command, msg.Body, err)
+		}
+	}
+}
+
+// send sends a request and returns its response, successful or not.
+func (s *testSession) send(command string, args interface{}) testMessage {
+	s.t.Helper()
+
+	s.seq++
+	seq := s.seq
+	data, err := json.Marshal(map[string]interface{}{
+		"seq":       seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": args,
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+
+	return s.next(command+" response", func(msg testMessage) bool {
+		return msg.Type == "response" && msg.RequestSeq == seq
+	})
+}
+
+// event waits for the next event with the given name.
+func (s *testSession) event(name string) testMessage {
+	s.t.Helper()
+	return s.next(name+" event", func(msg testMessage) bool {
+		return msg.Type == "event" && msg.Event == name
+	})
+}
+
+// stopped waits for the program to stop and returns the stop and the frame
+// it stopped in. The program ending first fails the test.
+func (s *testSession) stopped() (StoppedEventBody, StackFrame) {
+	s.t.Helper()
+
+	msg := s.next("a stop", func(msg testMessage) bool {
+		return msg.Type == "event" && (msg.Event == "stopped" || msg.Event == "terminated")
+	})
+	if msg.Event == "terminated" {
+		s.t.Fatal("program ended instead of stopping")
+	}
+	var stop StoppedEventBody
+	json.Unmarshal(msg.Body, &stop)
+
+	var trace StackTraceResponseBody
+	s.request("stackTrace", StackTraceArguments{ThreadID: 1}, &trace)
+	if len(trace.StackFrames) == 0 {
+		s.t.Fatal("stopped without a stack")
+	}
+	return stop, trace.StackFrames[0]
+}
+
+// resume continues the program.
+func (s *testSession) resume() {
+	s.t.Helper()
+	s.request("continue", ContinueArguments{ThreadID: 1}, nil)
+}
+
+// terminated waits for the program to end. A stop on the way fails the test.
+func (s *testSession) terminated() {
+	s.t.Helper()
+
+	msg := s.next("the program to end", func(msg testMessage) bool {
+		return msg.Type == "event" && (msg.Event == "stopped" || msg.Event == "terminated")
+	})
+	if msg.Event == "stopped" {
+		s.t.Fatalf("program stopped instead of ending: %s", msg.Body)
+	}
+}
+
+// setBreakpoints sets the source breakpoints of the program.
+func (s *testSession) setBreakpoints(breakpoints ...SourceBreakpoint) []Breakpoint {
+	s.t.Helper()
+
+	var body SetBreakpointsResponseBody
+	s.request("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: s.program},
+		Breakpoints: breakpoints,
+	}, &body)
+	return body.Breakpoints
+}
+
+// evaluate evaluates an expression in the top frame and returns its result.
+func (s *testSession) evaluate(expression string) string {
+	s.t.Helper()
+
+	var body EvaluateResponseBody
+	s.request("evaluate", EvaluateArguments{Expression: expression, FrameID: 1, Context: "watch"}, &body)
+	return body.Result
+}
+
+// output returns what the program and the adapter printed in a category.
+func (s *testSession) output(category string) string {
+	var out strings.Builder
+	for _, o := range s.outputs {
+		if o.Category == category {
+			out.WriteString(o.Output)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/token"
+)
+
+// Hovers and watches are evaluated without side effects. The expression is
+// refused when it assigns, updates or deletes something. Otherwise it is
+// rewritten so every call and new goes through a check that only lets
+// built-ins known to be free of side effects through, plus the functions the
+// expression defines itself. While it runs, the debugger refuses to enter
+// any other script code, which is how getters, valueOf and proxy traps are
+// kept from running unless the launch configuration sets allowGetters.
+
+// guardSourceName is the name the call helpers are compiled under, so their
+// frames are told apart from the program's.
+const guardSourceName = "<debugger>"
+
+// sideEffectFreeFunctions lists the built-ins hovers and watches may call,
+// by the object that holds them. Names missing from the engine are skipped.
+var sideEffectFreeFunctions = map[string][]string{
+	"": {"parseInt", "parseFloat", "isNaN", "isFinite", "String", "Number", "Boolean",
+		"encodeURI", "encodeURIComponent", "decodeURI", "decodeURIComponent"},
+	"Math": {"abs", "acos", "acosh", "asin", "asinh", "atan", "atanh", "atan2", "cbrt",
+		"ceil", "clz32", "cos", "cosh", "exp", "expm1", "floor", "fround", "hypot",
+		"imul", "log", "log1p", "log10", "log2", "max", "min", "pow", "round", "sign",
+		"sin", "sinh", "sqrt", "tan", "tanh", "trunc"},
+	"JSON": {"stringify", "parse"},
+	"Object": {"keys", "values", "entries", "fromEntries", "getOwnPropertyNames",
+		"getOwnPropertyDescriptor", "getPrototypeOf", "hasOwn", "is", "isFrozen",
+		"isSealed", "isExtensible"},
+	"Object.prototype": {"hasOwnProperty", "isPrototypeOf", "propertyIsEnumerable",
+		"toString", "toLocaleString", "valueOf"},
+	"Function.prototype": {"toString"},
+	"Array":              {"isArray", "of", "from"},
+	"Array.prototype": {"at", "concat", "entries", "every", "filter", "find", "findIndex",
+		"findLast", "findLastIndex", "flat", "flatMap", "forEach", "includes", "indexOf",
+		"join", "keys", "lastIndexOf", "map", "reduce", "reduceRight", "slice", "some",
+		"toLocaleString", "toReversed", "toSorted", "toSpliced", "toString", "values", "with"},
+	"String": {"fromCharCode", "fromCodePoint", "raw"},
+	"String.prototype": {"at", "charAt", "charCodeAt", "codePointAt", "concat", "endsWith",
+		"includes", "indexOf", "lastIndexOf", "localeCompare", "normalize", "padEnd",
+		"padStart", "repeat", "slice", "split", "startsWith", "substr", "substring",
+		"toLowerCase", "toUpperCase", "toLocaleLowerCase", "toLocaleUpperCase", "toString",
+		"trim", "trimStart", "trimEnd", "valueOf"},
+	"Number": {"isFinite", "isInteger", "isNaN", "isSafeInteger", "parseFloat", "parseInt"},
+	"Number.prototype": {"toExponential", "toFixed", "toLocaleString", "toPrecision",
+		"toString", "valueOf"},
+	"Boolean.prototype": {"toString", "valueOf"},
+	"Date":              {"now", "parse", "UTC"},
+	"Date.prototype": {"getDate", "getDay", "getFullYear", "getHours", "getMilliseconds",
+		"getMinutes", "getMonth", "getSeconds", "getTime", "getTimezoneOffset",
+		"getUTCDate", "getUTCDay", "getUTCFullYear", "getUTCHours", "getUTCMilliseconds",
+		"getUTCMinutes", "getUTCMonth", "getUTCSeconds", "toDateString", "toISOString",
+		"toJSON", "toLocaleDateString", "toLocaleString", "toLocaleTimeString",
+		"toString", "toTimeString", "toUTCString", "valueOf"},
+	"Map.prototype":     {"get", "has", "keys", "values", "entries", "forEach"},
+	"Set.prototype":     {"has", "keys", "values", "entries", "forEach"},
+	"WeakMap.prototype": {"get", "has"},
+	"WeakSet.prototype": {"has"},
+}
+
+// sideEffectFreeConstructors lists the built-ins hovers and watches may use
+// with new.
+var sideEffectFreeConstructors = []string{"Object", "Array", "Date", "Map", "Set",
+	"WeakMap", "WeakSet", "RegExp", "Error", "TypeError", "RangeError", "String",
+	"Number", "Boolean"}
+
+// callHelpers routes the calls of a rewritten expression through check,
+// which returns the function when it may be called. It is given the
+// built-ins it uses up front, the program could have replaced them.
+const callHelpers = `((apply, construct) => (check) => [
+	(f, args, label) => apply(check(f, args, false, label), undefined, args),
+	(o, key, args, label) => apply(check(o[key], args, false, label), o, args),
+	(f, args, label) => construct(check(f, args, true, label), args),
+])(Reflect.apply, Reflect.construct)`
+
+// sideEffectError is the reason an expression was refused.
+type sideEffectError struct {
+	reason string
+}
+
+func (e *sideEffectError) Error() string {
+	return "not evaluated to avoid side effects: " + e.reason
+}
+
+// sideEffectFree holds what the checks need from a runtime, built on first
+// use.
+type sideEffectFree struct {
+	functions    map[*goja.Object]bool
+	constructors map[*goja.Object]bool
+	helpers      goja.Callable
+}
+
+// sideEffectCheck is the state of one evaluation.
+type sideEffectCheck struct {
+	da      *DebugAdapter
+	created map[*goja.Object]bool // functions the expression defined
+	refusal *sideEffectError
+	depth   int    // call stack depth of the expression, 0 until it runs
+	srcName string // name the expression was compiled under
+}
+
+// evaluateWithoutSideEffects evaluates an expression for a hover or watch in
+// the selected frame, or the global scope without one. Engines that can't
+// read frames evaluate against the global scope, callers check it means the
+// same in the frame.
+func (da *DebugAdapter) evaluateWithoutSideEffects(expression string, frameID int) (goja.Value, error) {
+	prg, expr, err := parseExpressionProgram(expression)
+	if err != nil {
+		return nil, err
+	}
+	if reason := sideEffect(prg, expr); reason != "" {
+		return nil, &sideEffectError{reason: reason}
+	}
+
+	// The expression runs inside a pause, whatever the adapter had installed
+	// is put back for the program once it's done
+	defer da.swapDebugHandler(nil, false)()
+
+	safe, err := da.sideEffectFree()
+	if err != nil {
+		return nil, err
+	}
+
+	src := fmt.Sprintf("((__dbgCall, __dbgMethod, __dbgNew, __dbgFn) => (%s\n))", rewriteCalls(prg, expr))
+	var wrapper goja.Value
+	if frameID > 0 && da.frames.canReadFrames() {
+		wrapper, err = da.frames.evaluateInFrame(da.vm, src, frameIndex(frameID))
+	} else {
+		wrapper, err = da.vm.RunString(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	run, ok := goja.AssertFunction(wrapper)
+	if !ok {
+		return nil, fmt.Errorf("cannot evaluate '%s'", expression)
+	}
+
+	check := &sideEffectCheck{da: da, created: make(map[*goja.Object]bool)}
+	helpers, err := safe.helpers(goja.Undefined(), da.vm.ToValue(check.checkCall(safe)))
+	if err != nil {
+		return nil, err
+	}
+	h := helpers.ToObject(da.vm)
+
+	// Step through the expression to see every frame it enters. The
+	// deferred restore above puts the program's handler back.
+	if !da.launchArgs.AllowGetters {
+		da.swapDebugHandler(check.guard, true)
+	}
+	result, err := run(goja.Undefined(), h.Get("0"), h.Get("1"), h.Get("2"), da.vm.ToValue(check.defined))
+	da.vm.ClearInterrupt()
+
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) && check.refusal != nil {
+		return nil, check.refusal
+	}
+	return result, err
+}
+
+// sideEffectFree returns the functions and helpers of the current runtime.
+func (da *DebugAdapter) sideEffectFree() (*sideEffectFree, error) {
+	if da.safeEval != nil {
+		return da.safeEval, nil
+	}
+
+	safe := &sideEffectFree{
+		functions:    make(map[*goja.Object]bool),
+		constructors: make(map[*goja.Object]bool),
+	}
+	global := da.vm.GlobalObject()
+	for holder, names := range sideEffectFreeFunctions {
+		obj := global
+		if holder != "" {
+			obj = lookupObject(global, holder)
+		}
+		if obj == nil {
+			continue
+		}
+		for _, name := range names {
+			if fn, ok := obj.Get(name).(*goja.Object); ok {
+				safe.functions[fn] = true
+			}
+		}
+	}
+	for _, name := range sideEffectFreeConstructors {
+		if fn, ok := global.Get(name).(*goja.Object); ok {
+			safe.constructors[fn] = true
+		}
+	}
+
+	helpers, err := da.vm.RunScript(guardSourceName, callHelpers)
+	if err != nil {
+		return nil, err
+	}
+	safe.helpers, _ = goja.AssertFunction(helpers)
+
+	da.safeEval = safe
+	return safe, nil
+}
+
+// lookupObject follows a dotted path such as "Array.prototype" from obj.
+func lookupObject(obj *goja.Object, path string) *goja.Object {
+	for _, name := range strings.Split(path, ".") {
+		next, ok := obj.Get(name).(*goja.Object)
+		if !ok {
+			return nil
+		}
+		obj = next
+	}
+	return obj
+}
+
+// refuse stops the expression, the evaluation fails with reason.
+func (c *sideEffectCheck) refuse(reason string) {
+	if c.refusal == nil {
+		c.refusal = &sideEffectError{reason: reason}
+	}
+	c.da.vm.Interrupt(c.refusal)
+}
+
+// defined records a function the expression defines, it may be called.
+func (c *sideEffectCheck) defined(call goja.FunctionCall) goja.Value {
+	if fn, ok := call.Argument(0).(*goja.Object); ok {
+		c.created[fn] = true
+	}
+	return call.Argument(0)
+}
+
+// checkCall returns the check the call helpers run before every call: it
+// returns the function if it may be called with args, and refuses the
+// expression otherwise.
+func (c *sideEffectCheck) checkCall(safe *sideEffectFree) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		f := call.Argument(0)
+		construct := call.Argument(2).ToBoolean()
+		label := call.Argument(3).String()
+
+		fn, ok := f.(*goja.Object)
+		if _, callable := goja.AssertFunction(f); !ok || !callable {
+			// Calling it fails with the usual TypeError
+			return f
+		}
+
+		switch {
+		case construct && !safe.constructors[fn]:
+			c.refuse(fmt.Sprintf("it calls new %s", label))
+			return goja.Undefined()
+		case !construct && !safe.functions[fn] && !c.created[fn]:
+			c.refuse(fmt.Sprintf("it calls %s", label))
+			return goja.Undefined()
+		}
+
+		// Built-ins that take callbacks must not be handed other functions
+		if args, ok := call.Argument(1).(*goja.Object); ok {
+			for i := 0; i < arrayLength(args); i++ {
+				arg, ok := args.Get(strconv.Itoa(i)).(*goja.Object)
+				if !ok {
+					continue
+				}
+				if _, callable := goja.AssertFunction(arg); callable && !safe.functions[arg] && !c.created[arg] {
+					c.refuse(fmt.Sprintf("it passes a function to %s", label))
+					return goja.Undefined()
+				}
+			}
+		}
+		return f
+	}
+}
+
+// guard is the debug handler while the expression runs. Every instruction is
+// stepped into, and entering script code other than the expression's own
+// and the call helpers refuses it.
+func (c *sideEffectCheck) guard(state *debugState) debugCommand {
+	if len(state.CallStack) == 0 {
+		return debugStepInto
+	}
+	top := state.CallStack[0]
+	if c.depth == 0 {
+		// The first instruction is in the expression itself
+		c.depth = len(state.CallStack)
+		c.srcName = top.SrcName()
+		return debugStepInto
+	}
+	if len(state.CallStack) <= c.depth {
+		return debugStepInto
+	}
+
+	switch name := top.SrcName(); {
+	case name == "<native>", name == guardSourceName:
+	case name == c.srcName && !c.da.isScriptName(name):
+		// A function the expression defines. When the engine compiles the
+		// expression under the name of a script, they can't be told apart
+		// from the script's and are refused too.
+	default:
+		funcName := top.FuncName()
+		if funcName == "" {
+			funcName = "(anonymous)"
+		}
+		pos := top.Position()
+		c.refuse(fmt.Sprintf("it runs %s (%s:%d)", funcName, filepath.Base(pos.Filename), pos.Line))
+	}
+	return debugStepInto
+}
+
+// isScriptName reports whether name is the name of a script the runtime
+// loaded.
+func (da *DebugAdapter) isScriptName(name string) bool {
+	da.scriptsMutex.Lock()
+	defer da.scriptsMutex.Unlock()
+	for _, s := range da.loadedScripts {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sideEffect returns why an expression can't be evaluated without side
+// effects, or "" when its calls are the only thing left to check.
+func sideEffect(prg *ast.Program, expr ast.Expression) string {
+	var reason string
+	walkAST(expr, func(node ast.Node) bool {
+		if reason != "" {
+			return false
+		}
+		switch n := node.(type) {
+		case *ast.AssignExpression:
+			reason = fmt.Sprintf("it assigns to %s", nodeSource(prg, n.Left))
+		case *ast.UnaryExpression:
+			switch n.Operator {
+			case token.INCREMENT, token.DECREMENT:
+				reason = fmt.Sprintf("it updates %s", nodeSource(prg, n.Operand))
+			case token.DELETE:
+				reason = fmt.Sprintf("it deletes %s", nodeSource(prg, n.Operand))
+			}
+		case *ast.TemplateLiteral:
+			if n.Tag != nil {
+				reason = fmt.Sprintf("it calls %s", nodeSource(prg, n.Tag))
+			}
+		case *ast.ClassLiteral:
+			reason = "it defines a class"
+		case *ast.AwaitExpression:
+			reason = "it awaits"
+		case *ast.YieldExpression:
+			reason = "it yields"
+		case *ast.OptionalChain:
+			if call := optionalCall(n.Expression); call != nil {
+				reason = fmt.Sprintf("it calls %s", nodeSource(prg, call.Callee))
+			}
+		case *ast.CallExpression:
+			switch callee := n.Callee.(type) {
+			case *ast.PrivateDotExpression:
+				reason = fmt.Sprintf("it calls %s", nodeSource(prg, callee))
+			case *ast.DotExpression:
+				if _, ok := callee.Left.(*ast.SuperExpression); ok {
+					reason = fmt.Sprintf("it calls %s", nodeSource(prg, callee))
+				}
+			case *ast.SuperExpression:
+				reason = "it calls super"
+			}
+		}
+		return reason == ""
+	})
+	return reason
+}
+
+// optionalCall returns a call that is part of an optional chain such as
+// `a?.b()`, they can't be routed through the call helpers without changing
+// where the chain stops.
+func optionalCall(expr ast.Expression) *ast.CallExpression {
+	for expr != nil {
+		switch e := expr.(type) {
+		case *ast.CallExpression:
+			return e
+		case *ast.Optional:
+			expr = e.Expression
+		case *ast.DotExpression:
+			expr = e.Left
+		case *ast.BracketExpression:
+			expr = e.Left
+		case *ast.PrivateDotExpression:
+			expr = e.Left
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// rewriteCalls returns the source of an expression with every call going
+// through the helpers the evaluation wrapper declares, and every function it
+// defines recorded:
+//
+//	a.b(x)     __dbgMethod((a), "b", [x], "a.b")
+//	f(x)       __dbgCall((f), [x], "f")
+//	new C(x)   __dbgNew((C), [x], "C")
+//	x => x * 2 __dbgFn(x => x * 2)
+func rewriteCalls(prg *ast.Program, node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.CallExpression:
+		args := rewriteRange(prg, n.LeftParenthesis+1, n.RightParenthesis, expressionNodes(n.ArgumentList))
+		switch callee := n.Callee.(type) {
+		case *ast.DotExpression:
+			name := callee.Identifier.Name.String()
+			return fmt.Sprintf("__dbgMethod((%s), %s, [%s], %s)", rewriteCalls(prg, callee.Left), jsString(name), args,
+				jsString(nodeSource(prg, callee.Left)+"."+name))
+		case *ast.BracketExpression:
+			return fmt.Sprintf("__dbgMethod((%s), (%s), [%s], %s)", rewriteCalls(prg, callee.Left), rewriteCalls(prg, callee.Member), args,
+				jsString(nodeSource(prg, callee.Left)+"["+nodeSource(prg, callee.Member)+"]"))
+		}
+		return fmt.Sprintf("__dbgCall((%s), [%s], %s)", rewriteCalls(prg, n.Callee), args, jsString(nodeSource(prg, n.Callee)))
+	case *ast.NewExpression:
+		var args string
+		if n.ArgumentList != nil {
+			args = rewriteRange(prg, n.LeftParenthesis+1, n.RightParenthesis, expressionNodes(n.ArgumentList))
+		}
+		return fmt.Sprintf("__dbgNew((%s), [%s], %s)", rewriteCalls(prg, n.Callee), args, jsString(nodeSource(prg, n.Callee)))
+	case *ast.FunctionLiteral:
+		return fmt.Sprintf("__dbgFn(%s)", rewriteRange(prg, n.Idx0(), n.Idx1(), []ast.Node{n.ParameterList, n.Body}))
+	case *ast.ArrowFunctionLiteral:
+		return fmt.Sprintf("__dbgFn(%s)", rewriteRange(prg, n.Idx0(), n.Idx1(), []ast.Node{n.ParameterList, n.Body}))
+	}
+	return rewriteRange(prg, node.Idx0(), node.Idx1(), []ast.Node{node})
+}
+
+// rewriteRange returns the source between start and end with the calls and
+// functions in nodes rewritten.
+func rewriteRange(prg *ast.Program, start, end file.Idx, nodes []ast.Node) string {
+	var rewritten []ast.Node
+	for _, root := range nodes {
+		walkAST(root, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.PropertyKeyed:
+				if n.Kind != ast.PropertyKindValue {
+					// A method's function starts at its key, it is left in
+					// place and only its body rewritten
+					if n.Computed {
+						rewritten = append(rewritten, n.Key)
+					}
+					if fn, ok := n.Value.(*ast.FunctionLiteral); ok && fn.Body != nil {
+						rewritten = append(rewritten, fn.Body)
+					}
+					return false
+				}
+			case *ast.CallExpression, *ast.NewExpression, *ast.FunctionLiteral, *ast.ArrowFunctionLiteral:
+				rewritten = append(rewritten, node)
+				return false
+			}
+			return true
+		})
+	}
+	sort.Slice(rewritten, func(i, j int) bool { return rewritten[i].Idx0() < rewritten[j].Idx0() })
+
+	src := prg.File.Source()
+	base := prg.File.Base()
+	var b strings.Builder
+	pos := int(start) - base
+	for _, node := range rewritten {
+		b.WriteString(src[pos : int(node.Idx0())-base])
+		if block, ok := node.(*ast.BlockStatement); ok {
+			b.WriteString(rewriteRange(prg, block.Idx0(), block.Idx1(), []ast.Node{block}))
+		} else {
+			b.WriteString(rewriteCalls(prg, node))
+		}
+		pos = int(node.Idx1()) - base
+	}
+	b.WriteString(src[pos : int(end)-base])
+	return b.String()
+}
+
+func expressionNodes(list []ast.Expression) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i, expr := range list {
+		nodes[i] = expr
+	}
+	return nodes
+}
+
+// jsString quotes s as a JS string literal.
+func jsString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
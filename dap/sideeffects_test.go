@@ -0,0 +1,103 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const sideEffectProgram = `var counter = 0;
+function bump() { return ++counter; }
+var obj = {};
+Object.defineProperty(obj, "lazy", { get: function () { counter += 10; return "loaded"; } });
+console.log("paused");
+`
+
+// hover evaluates an expression the way a hover does. A refused or failed
+// evaluation returns its message and false.
+func (s *testSession) hover(expression string) (string, bool) {
+	s.t.Helper()
+
+	msg := s.send("evaluate", EvaluateArguments{Expression: expression, FrameID: 1, Context: "hover"})
+	if !msg.Success {
+		var body map[string]string
+		json.Unmarshal(msg.Body, &body)
+		return body["error"], false
+	}
+	var body EvaluateResponseBody
+	json.Unmarshal(msg.Body, &body)
+	return body.Result, true
+}
+
+func TestHoverRefusesSideEffects(t *testing.T) {
+	s := newTestSession(t, sideEffectProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 5})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	for _, expr := range []string{"bump()", "counter++", "counter = 5", "obj.lazy"} {
+		if result, ok := s.hover(expr); ok || !strings.Contains(result, "side effects") {
+			t.Errorf("hover %s = %q, %v; want it refused", expr, result, ok)
+		}
+	}
+	if result, ok := s.hover("Math.max(counter, 2) + [1, 2].length"); !ok || result != "4" {
+		t.Errorf("hover of side-effect-free calls = %q, %v; want 4", result, ok)
+	}
+	if got := s.evaluate("counter"); got != "0" {
+		t.Errorf("counter = %s after the hovers, want 0", got)
+	}
+
+	s.resume()
+	s.terminated()
+}
+
+func TestHoverRunsGettersWhenAllowed(t *testing.T) {
+	s := launchTestSession(t, sideEffectProgram, LaunchRequestArguments{AllowGetters: true})
+	s.setBreakpoints(SourceBreakpoint{Line: 5})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	if result, ok := s.hover("obj.lazy"); !ok || result != "loaded" {
+		t.Errorf("hover obj.lazy = %q, %v; want the getter's value", result, ok)
+	}
+	if _, ok := s.hover("bump()"); ok {
+		t.Error("allowGetters let a hover call a function")
+	}
+
+	s.resume()
+	s.terminated()
+}
+
+func TestGetterOfThrownValueDoesNotPause(t *testing.T) {
+	s := launchTestSession(t, `Object.defineProperty(globalThis, "failure", {
+  get: function () {
+    return new Error("boom");
+  }
+});
+try {
+  throw failure;
+} catch (e) {
+  console.log(e.message);
+}
+`, LaunchRequestArguments{AllowGetters: true})
+
+	s.setBreakpoints(SourceBreakpoint{Line: 3})
+	s.request("setExceptionBreakpoints", SetExceptionBreakpointsArguments{Filters: []string{"all"}}, nil)
+	s.request("configurationDone", nil, nil)
+
+	// The program's own read of failure stops in the getter. Reading it
+	// again to describe the exception must not.
+	for _, want := range []struct {
+		reason string
+		line   int
+	}{{"breakpoint", 3}, {"exception", 7}} {
+		stop, frame := s.stopped()
+		if stop.Reason != want.reason || frame.Line != want.line {
+			t.Fatalf("stopped for %q at line %d; want %s at line %d", stop.Reason, frame.Line, want.reason, want.line)
+		}
+		s.resume()
+	}
+	s.terminated()
+}
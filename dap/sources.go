@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+)
+
+// evalSourceName is the name goja compiles eval and new Function code under.
+const evalSourceName = "<eval>"
+
+// consoleOrigin is the origin of code typed in the Debug Console. It is
+// shown when one of its frames is on the stack, but isn't a loaded script.
+const consoleOrigin = "Debug Console"
+
+// loadedScript is a script compiled in the runtime. Scripts without a file
+// on disk, like code typed in the Debug Console or passed to eval, get a
+// sourceReference so the client can fetch their text with the source request.
+type loadedScript struct {
+	name      string // goja source name
+	path      string // file on disk, "" for generated code
+	text      string
+	reference int // DAP sourceReference, 0 for files
+	origin    string
+	positions []file.Position // compiled positions, computed on demand
+}
+
+// key is the name the script's breakpoints are stored under. All eval code
+// runs under the same goja name, each text is told apart by its reference.
+func (s *loadedScript) key() string {
+	if s.name == evalSourceName {
+		return fmt.Sprintf("%s#%d", s.name, s.reference)
+	}
+	return s.name
+}
+
+// listed reports whether the script is shown in the Loaded Scripts view.
+func (s *loadedScript) listed() bool {
+	return s.origin != consoleOrigin
+}
+
+// source describes the script for the client.
+func (s *loadedScript) source() Source {
+	if s.path != "" {
+		return Source{
+			Name: filepath.Base(s.path),
+			Path: s.path,
+		}
+	}
+	return Source{
+		Name:            s.name,
+		SourceReference: s.reference,
+		Origin:          s.origin,
+	}
+}
+
+func (da *DebugAdapter) handleSource(req *Request) {
+	var args SourceArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	ref := args.SourceReference
+	if args.Source != nil && args.Source.SourceReference > 0 {
+		ref = args.Source.SourceReference
+	}
+
+	log.Printf("Source request for reference %d", ref)
+
+	script := da.scriptByRef(ref)
+	if script == nil && ref == 0 && args.Source != nil && args.Source.Path != "" {
+		script = da.scriptByName(args.Source.Path)
+	}
+	if script == nil {
+		da.sendErrorResponse(req, fmt.Errorf("source %d is not available", ref))
+		return
+	}
+
+	da.sendResponse(req.Seq, req.Command, true, SourceResponseBody{
+		Content:  script.text,
+		MimeType: "text/javascript",
+	})
+}
+
+func (da *DebugAdapter) handleLoadedSources(req *Request) {
+	da.scriptsMutex.Lock()
+	sources := []Source{}
+	for _, script := range da.loadedScripts {
+		if script.listed() {
+			sources = append(sources, script.source())
+		}
+	}
+	da.scriptsMutex.Unlock()
+
+	log.Printf("LoadedSources request: %d sources", len(sources))
+
+	da.sendResponse(req.Seq, req.Command, true, LoadedSourcesResponseBody{
+		Sources: sources,
+	})
+}
+
+// runConsoleScript runs code typed in the Debug Console under a name of its
+// own, so functions it defines can be shown when the program calls them.
+func (da *DebugAdapter) runConsoleScript(src string) (goja.Value, error) {
+	da.scriptsMutex.Lock()
+	da.consoleScripts++
+	name := fmt.Sprintf("<console %d>", da.consoleScripts)
+	da.scriptsMutex.Unlock()
+
+	da.parseScript(name, src)
+	da.addScript(name, "", src, consoleOrigin)
+
+	return da.vm.RunScript(name, src)
+}
+
+// addScript registers a script, tells the client about it unless it is
+// Debug Console input, and places the breakpoints waiting for it. Registering
+// a name again with new text updates the script, except for eval code, where
+// every text is a script of its own. References stay valid for the whole
+// session.
+func (da *DebugAdapter) addScript(name, path, text, origin string) *loadedScript {
+	da.scriptsMutex.Lock()
+
+	var script *loadedScript
+	reason := "new"
+	for _, s := range da.loadedScripts {
+		if s.name != name || (name == evalSourceName && s.text != text) {
+			continue
+		}
+		if s.text == text {
+			da.scriptsMutex.Unlock()
+			return s
+		}
+		script = s
+		reason = "changed"
+		break
+	}
+
+	if script == nil {
+		script = &loadedScript{
+			name:   name,
+			path:   path,
+			origin: origin,
+		}
+		if path == "" {
+			da.sourceRefCounter++
+			script.reference = da.sourceRefCounter
+		}
+		da.loadedScripts = append(da.loadedScripts, script)
+	}
+	script.text = text
+	script.positions = nil
+	source := script.source()
+	da.scriptsMutex.Unlock()
+
+	log.Printf("Loaded source %s (%s), reference %d", name, reason, script.reference)
+	if script.listed() {
+		da.sendEvent("loadedSource", LoadedSourceEventBody{
+			Reason: reason,
+			Source: source,
+		})
+	}
+	da.reresolveBreakpoints(script.key())
+	da.placeFunctionBreakpoints()
+	da.placeThrowBreakpoints()
+	return script
+}
+
+// removeScripts forgets every script of the previous runtime.
+func (da *DebugAdapter) removeScripts() {
+	da.scriptsMutex.Lock()
+	scripts := da.loadedScripts
+	da.loadedScripts = nil
+	da.scriptASTs = make(map[string]*ast.Program)
+	da.evalScripts = make(map[uintptr]*loadedScript)
+	da.consoleScripts = 0
+	da.scriptsMutex.Unlock()
+
+	for _, script := range scripts {
+		if !script.listed() {
+			continue
+		}
+		da.sendEvent("loadedSource", LoadedSourceEventBody{
+			Reason: "removed",
+			Source: script.source(),
+		})
+	}
+}
+
+func (da *DebugAdapter) scriptByRef(ref int) *loadedScript {
+	da.scriptsMutex.Lock()
+	defer da.scriptsMutex.Unlock()
+
+	if ref <= 0 {
+		return nil
+	}
+	for _, s := range da.loadedScripts {
+		if s.reference == ref {
+			return s
+		}
+	}
+	return nil
+}
+
+// scriptByName finds a registered script by its goja name. Eval code all
+// shares one name, so it is never looked up this way.
+func (da *DebugAdapter) scriptByName(name string) *loadedScript {
+	da.scriptsMutex.Lock()
+	defer da.scriptsMutex.Unlock()
+
+	if name == evalSourceName {
+		return nil
+	}
+	for _, s := range da.loadedScripts {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// discoverScript registers a file that host code ran without going through
+// the adapter, the first time one of its frames shows up.
+func (da *DebugAdapter) discoverScript(name string) *loadedScript {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	da.parseScript(name, string(content))
+	return da.addScript(name, name, string(content), "")
+}
+
+// frameSource returns the Source of stack frame i for the Call Stack view.
+func (da *DebugAdapter) frameSource(stack []goja.StackFrame, i int) Source {
+	pos := stack[i].Position()
+
+	var script *loadedScript
+	switch pos.Filename {
+	case evalSourceName:
+		script = da.evalScript(&stack[i])
+		if script == nil {
+			return Source{
+				Name:             evalSourceName,
+				Origin:           "eval",
+				PresentationHint: "deemphasize",
+			}
+		}
+	case "":
+		// Native code, or a script run without a name
+	default:
+		script = da.scriptByName(pos.Filename)
+		if script == nil {
+			script = da.discoverScript(pos.Filename)
+		}
+	}
+
+	if script != nil {
+		return script.source()
+	}
+
+	return Source{
+		Name: filepath.Base(pos.Filename),
+		Path: pos.Filename,
+	}
+}
+
+// evalScript returns the script of the eval or new Function code running in
+// frame, registering it the first time it is seen.
+func (da *DebugAdapter) evalScript(frame *goja.StackFrame) *loadedScript {
+	text, code, ok := compiledSource(frame)
+	if !ok {
+		return nil
+	}
+
+	da.scriptsMutex.Lock()
+	script := da.evalScripts[code]
+	da.scriptsMutex.Unlock()
+	if script != nil && script.text == text {
+		return script
+	}
+
+	script = da.addScript(evalSourceName, "", text, "eval")
+	da.scriptsMutex.Lock()
+	da.evalScripts[code] = script
+	da.scriptsMutex.Unlock()
+	return script
+}
+
+// compiledSource returns the text goja compiled the code of a frame from,
+// and the address of its source file, which tells compilations apart. goja
+// keeps no other copy of eval and new Function code and doesn't export this
+// one, so it is read with reflection like the source map (see
+// GOJA_DEBUG_PROPOSAL.md).
+func compiledSource(frame *goja.StackFrame) (text string, code uintptr, ok bool) {
+	prg := reflect.ValueOf(frame).Elem().FieldByName("prg")
+	if !prg.IsValid() || prg.Type() != programType || prg.IsNil() {
+		return "", 0, false
+	}
+	src := prg.Elem().FieldByName("src")
+	if src.Kind() != reflect.Ptr || src.IsNil() {
+		return "", 0, false
+	}
+	srcText := src.Elem().FieldByName("src")
+	if srcText.Kind() != reflect.String {
+		return "", 0, false
+	}
+	return srcText.String(), src.Pointer(), true
+}
@@ -0,0 +1,71 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const evalProgram = `function probe() {
+  var one = 1;
+  return one;
+}
+eval("var fromEval = 1;\nfromEval += probe();\n");
+`
+
+func TestEvalSource(t *testing.T) {
+	s := newTestSession(t, evalProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 2})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	// The caller of probe() is the eval code, which has no file
+	var trace StackTraceResponseBody
+	s.request("stackTrace", StackTraceArguments{ThreadID: 1}, &trace)
+	caller := trace.StackFrames[1]
+	if caller.Source.Path != "" || caller.Source.SourceReference == 0 || caller.Line != 2 {
+		t.Fatalf("eval frame = %+v; want line 2 of a source without a path", caller)
+	}
+
+	var body SourceResponseBody
+	s.request("source", SourceArguments{Source: &caller.Source, SourceReference: caller.Source.SourceReference}, &body)
+	if want := "var fromEval = 1;\nfromEval += probe();\n"; body.Content != want {
+		t.Errorf("source of the eval code = %q, want %q", body.Content, want)
+	}
+	if msg := s.send("source", SourceArguments{SourceReference: 99}); msg.Success {
+		t.Error("an unknown source reference was answered")
+	}
+
+	s.resume()
+	s.terminated()
+}
+
+func TestLoadedSources(t *testing.T) {
+	s := newTestSession(t, evalProgram)
+	s.setBreakpoints(SourceBreakpoint{Line: 2})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	// Console input is no source of the program
+	var result EvaluateResponseBody
+	s.request("evaluate", EvaluateArguments{Expression: "function typed() { return 2; }", FrameID: 1, Context: "repl"}, &result)
+
+	var body LoadedSourcesResponseBody
+	s.request("loadedSources", nil, &body)
+	if len(body.Sources) != 2 || body.Sources[0].Path != s.program || body.Sources[1].Origin != "eval" {
+		t.Errorf("loaded sources = %+v, want the program and its eval code", body.Sources)
+	}
+
+	// Each was announced once it was loaded
+	for _, want := range body.Sources {
+		var event LoadedSourceEventBody
+		json.Unmarshal(s.event("loadedSource").Body, &event)
+		if event.Reason != "new" || event.Source != want {
+			t.Errorf("loadedSource event %q for %+v, want new for %+v", event.Reason, event.Source, want)
+		}
+	}
+
+	s.resume()
+	s.terminated()
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+)
+
+// callTarget is a call expression on the paused line that can be stepped
+// into.
+type callTarget struct {
+	id    int
+	label string
+	start file.Idx
+	end   file.Idx
+}
+
+// stepTarget tracks a stepIn heading for one of the calls on a line.
+type stepTarget struct {
+	target   callTarget
+	calls    []callTarget // every call on the line, to tell which one was entered
+	filename string
+	line     int
+	depth    int // call stack depth of the paused frame
+}
+
+func (da *DebugAdapter) handleStepInTargets(req *Request) {
+	var args StepInTargetsArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	frame, ok := da.frameMap[args.FrameID]
+	if !ok {
+		da.sendErrorResponse(req, fmt.Errorf("unknown frame %d", args.FrameID))
+		return
+	}
+	pos := frame.Position()
+
+	da.scriptsMutex.Lock()
+	prg := da.scriptASTs[pos.Filename]
+	da.scriptsMutex.Unlock()
+
+	var targets []StepInTarget
+	da.stepInCalls = nil
+	da.stepInFrameID = args.FrameID
+	if prg != nil {
+		da.stepInCalls = findCallsOnLine(prg, pos.Line)
+		for _, call := range da.stepInCalls {
+			start := nodePosition(prg, call.start)
+			end := nodePosition(prg, call.end)
+			targets = append(targets, StepInTarget{
+				ID:        call.id,
+				Label:     call.label,
+				Line:      start.Line,
+				Column:    da.clientColumn(start.Column),
+				EndLine:   end.Line,
+				EndColumn: da.clientColumn(end.Column),
+			})
+		}
+	}
+
+	log.Printf("StepInTargets for %s:%d: %d targets", pos.Filename, pos.Line, len(targets))
+
+	da.sendResponse(req.Seq, req.Command, true, StepInTargetsResponseBody{
+		Targets: targets,
+	})
+}
+
+// setStepInTarget prepares a stepIn that only stops inside the call chosen
+// from stepInTargets. Other calls on the line are stepped over.
+func (da *DebugAdapter) setStepInTarget(targetID int) {
+	da.stepTarget = nil
+	if targetID == 0 {
+		return
+	}
+
+	frame, ok := da.frameMap[da.stepInFrameID]
+	if !ok {
+		return
+	}
+	pos := frame.Position()
+
+	for _, call := range da.stepInCalls {
+		if call.id == targetID {
+			da.stepTarget = &stepTarget{
+				target:   call,
+				calls:    da.stepInCalls,
+				filename: pos.Filename,
+				line:     pos.Line,
+				// frameMap IDs are 1-based from the top of the stack
+				// of the pause, which may be below the VM's, e.g. in the
+				// accessor of a data breakpoint
+				depth: len(da.pausedStack()) - da.stepInFrameID + 1,
+			}
+			log.Printf("Stepping into target %d (%s)", call.id, call.label)
+			return
+		}
+	}
+}
+
+// stepTargetCommand is consulted by the debug handler while a targeted stepIn
+// is in progress. It returns the command to keep going with, or false once
+// execution has reached the target (or can no longer reach it) and should
+// stop.
+func (da *DebugAdapter) stepTargetCommand(state *debugState) (debugCommand, bool) {
+	st := da.stepTarget
+	stack := state.CallStack
+	depth := len(stack)
+
+	switch {
+	case depth > st.depth:
+		// Entered a function, find the call it was entered from. The
+		// paused frame reports the position of the call's parenthesis.
+		caller := stack[depth-st.depth].Position()
+		if depth == st.depth+1 && caller.Filename == st.filename {
+			da.scriptsMutex.Lock()
+			prg := da.scriptASTs[st.filename]
+			da.scriptsMutex.Unlock()
+
+			if prg != nil {
+				idx := positionIdx(prg, caller.Line, caller.Column)
+				if call, ok := innermostCall(st.calls, idx); ok && call.id == st.target.id {
+					da.stepTarget = nil
+					return 0, false
+				}
+			}
+		}
+		// Some other call, step over its statements until it returns.
+		// DebugStepOut would also leave the paused frame.
+		return debugStepOver, true
+
+	case depth == st.depth:
+		pos := stack[0].Position()
+		if pos.Filename == st.filename && pos.Line == st.line {
+			return debugStepInto, true
+		}
+	}
+
+	// Left the line without entering the target, e.g. a native function
+	da.stepTarget = nil
+	return 0, false
+}
+
+// findCallsOnLine lists the calls that start on line, in the order they are
+// made: arguments are evaluated before the call that receives them.
+func findCallsOnLine(prg *ast.Program, line int) []callTarget {
+	var calls []callTarget
+
+	var visit func(node ast.Node) bool
+	visit = func(node ast.Node) bool {
+		var callee ast.Expression
+		prefix := ""
+		switch n := node.(type) {
+		case *ast.CallExpression:
+			callee = n.Callee
+		case *ast.NewExpression:
+			callee = n.Callee
+			prefix = "new "
+		default:
+			return true
+		}
+
+		// Visit the callee and arguments first so nested calls come before
+		// the call that uses their result
+		for _, child := range childNodes(node) {
+			walkAST(child, visit)
+		}
+
+		if nodePosition(prg, node.Idx0()).Line == line {
+			label := expressionName(callee)
+			if label == "" {
+				label = nodeSource(prg, callee)
+			}
+			calls = append(calls, callTarget{
+				id:    len(calls) + 1,
+				label: prefix + label,
+				start: node.Idx0(),
+				end:   node.Idx1(),
+			})
+		}
+		return false
+	}
+	walkAST(prg, visit)
+
+	return calls
+}
+
+// childNodes returns the callee and arguments of a call.
+func childNodes(node ast.Node) []ast.Node {
+	var children []ast.Node
+	switch n := node.(type) {
+	case *ast.CallExpression:
+		children = append(children, n.Callee)
+		for _, arg := range n.ArgumentList {
+			children = append(children, arg)
+		}
+	case *ast.NewExpression:
+		children = append(children, n.Callee)
+		for _, arg := range n.ArgumentList {
+			children = append(children, arg)
+		}
+	}
+	return children
+}
+
+// innermostCall returns the smallest call whose source range contains idx.
+func innermostCall(calls []callTarget, idx file.Idx) (callTarget, bool) {
+	var best callTarget
+	found := false
+	for _, call := range calls {
+		if idx < call.start || idx >= call.end {
+			continue
+		}
+		if !found || call.end-call.start < best.end-best.start {
+			best = call
+			found = true
+		}
+	}
+	return best, found
+}
+
+// handleGotoTargets and handleGoto answer "set next statement" requests.
+// Moving the execution point means changing the VM's program counter, which
+// the goja debugger doesn't expose, so SupportsGotoTargetsRequest stays false.
+// See GOJA_DEBUG_PROPOSAL.md for the engine support this needs.
+func (da *DebugAdapter) handleGotoTargets(req *Request) {
+	var args GotoTargetsArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	log.Printf("GotoTargets request for %s:%d: not supported by the goja debugger", args.Source.Path, args.Line)
+
+	da.sendErrorResponse(req, fmt.Errorf("jumping to line %d is not supported: the goja debugger cannot move the execution point", args.Line))
+}
+
+func (da *DebugAdapter) handleGoto(req *Request) {
+	var args GotoArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	log.Printf("Goto request for target %d: not supported by the goja debugger", args.TargetID)
+
+	da.sendErrorResponse(req, fmt.Errorf("goto is not supported: the goja debugger cannot move the execution point"))
+}
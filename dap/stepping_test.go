@@ -0,0 +1,56 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"testing"
+)
+
+// stepInTargets returns the calls that can be stepped into from a frame.
+func (s *testSession) stepInTargets(frameID int) []StepInTarget {
+	s.t.Helper()
+
+	var body StepInTargetsResponseBody
+	s.request("stepInTargets", StepInTargetsArguments{FrameID: frameID}, &body)
+	return body.Targets
+}
+
+func TestStepInTargets(t *testing.T) {
+	columnsStartAt1 := false
+	s := startTestSession(t, `function foo(y) {
+  return y * 2;
+}
+function bar(v) {
+  return v;
+}
+bar(foo(2));
+`, sessionOptions{initialize: InitializeRequestArguments{ColumnsStartAt1: &columnsStartAt1}})
+
+	s.setBreakpoints(SourceBreakpoint{Line: 7})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	// In the order the calls are made, columns counted from 0
+	targets := s.stepInTargets(1)
+	want := []StepInTarget{
+		{Label: "foo", Line: 7, Column: 4, EndLine: 7, EndColumn: 10},
+		{Label: "bar", Line: 7, Column: 0, EndLine: 7, EndColumn: 11},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("targets = %+v, want %+v", targets, want)
+	}
+	for i := range want {
+		want[i].ID = targets[i].ID
+		if targets[i] != want[i] {
+			t.Errorf("target %d = %+v, want %+v", i, targets[i], want[i])
+		}
+	}
+
+	// foo is stepped over on the way into bar
+	s.request("stepIn", StepInArguments{ThreadID: 1, TargetID: targets[1].ID}, nil)
+	if _, frame := s.stopped(); frame.Name != "bar" {
+		t.Errorf("stepped into %s, want bar", frame.Name)
+	}
+	s.resume()
+	s.terminated()
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+)
+
+func (da *DebugAdapter) handleSetVariable(req *Request) {
+	var args SetVariableArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	log.Printf("SetVariable request: ref=%d, name=%s, value=%s", args.VariablesReference, args.Name, args.Value)
+
+	val, err := da.setVariable(args.VariablesReference, args.Name, args.Value)
+	if err != nil {
+		log.Printf("SetVariable failed: %v", err)
+		da.sendErrorResponse(req, err)
+		return
+	}
+
+	v := da.describeValue(args.Name, val)
+	da.sendResponse(req.Seq, req.Command, true, SetVariableResponseBody{
+		Value:              v.Value,
+		Type:               v.Type,
+		VariablesReference: v.VariablesReference,
+		NamedVariables:     v.NamedVariables,
+		IndexedVariables:   v.IndexedVariables,
+	})
+}
+
+func (da *DebugAdapter) handleSetExpression(req *Request) {
+	var args SetExpressionArguments
+	if req.Arguments != nil {
+		data, _ := json.Marshal(req.Arguments)
+		json.Unmarshal(data, &args)
+	}
+
+	log.Printf("SetExpression request: expression=%s, value=%s, frame=%d", args.Expression, args.Value, args.FrameID)
+
+	val, err := da.setExpression(args.Expression, args.Value, args.FrameID)
+	if err != nil {
+		log.Printf("SetExpression failed: %v", err)
+		da.sendErrorResponse(req, err)
+		return
+	}
+
+	v := da.describeValue(args.Expression, val)
+	da.sendResponse(req.Seq, req.Command, true, SetExpressionResponseBody{
+		Value:              v.Value,
+		Type:               v.Type,
+		VariablesReference: v.VariablesReference,
+		NamedVariables:     v.NamedVariables,
+		IndexedVariables:   v.IndexedVariables,
+	})
+}
+
+// setVariable assigns value, a JS expression, to the variable name in the
+// container identified by ref: a scope or an expanded object.
+func (da *DebugAdapter) setVariable(ref int, name, value string) (goja.Value, error) {
+	if da.vm == nil || (da.running && !da.isPaused()) {
+		return nil, fmt.Errorf("variables can only be changed while paused")
+	}
+	if err := checkExpression(value); err != nil {
+		return nil, err
+	}
+
+	container, ok := da.varRefMap[ref]
+	if !ok {
+		return nil, fmt.Errorf("unknown variables reference %d", ref)
+	}
+
+	switch c := container.(type) {
+	case map[string]interface{}:
+		switch c["type"] {
+		case "local":
+			if !isIdentifier(name) {
+				return nil, fmt.Errorf("cannot assign to '%s'", name)
+			}
+			return da.setLocalVariable(frameIndex(c["frameID"].(int)), name, value)
+		case "global":
+			val, err := da.evaluateGlobal(value)
+			if err != nil {
+				return nil, err
+			}
+			return val, da.setProperty(da.vm.GlobalObject(), name, val)
+		}
+	case goja.Value:
+		obj, ok := c.(*goja.Object)
+		if !ok {
+			break
+		}
+		val, err := da.evaluateGlobal(value)
+		if err != nil {
+			return nil, err
+		}
+		return val, da.setProperty(obj, name, val)
+	case *arrayChunk:
+		val, err := da.evaluateGlobal(value)
+		if err != nil {
+			return nil, err
+		}
+		return val, da.setProperty(c.obj, name, val)
+	case *frameScope:
+		return da.setScopeVariable(c, name, value)
+	}
+
+	return nil, fmt.Errorf("'%s' can't be modified", name)
+}
+
+// setExpression assigns value to an assignable expression such as a watch
+// entry, as the given frame sees it.
+func (da *DebugAdapter) setExpression(expression, value string, frameID int) (goja.Value, error) {
+	if da.vm == nil || (da.running && !da.isPaused()) {
+		return nil, fmt.Errorf("expressions can only be changed while paused")
+	}
+	if err := checkExpression(value); err != nil {
+		return nil, err
+	}
+
+	expr, err := parseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	switch expr.(type) {
+	case *ast.Identifier, *ast.DotExpression, *ast.BracketExpression:
+	default:
+		return nil, fmt.Errorf("'%s' is not assignable", expression)
+	}
+
+	assignment := fmt.Sprintf("%s = (%s)", expression, value)
+	if frameID > 0 {
+		if err := da.checkFrameEvaluation(assignment, frameID); err != nil {
+			return nil, err
+		}
+	}
+	return da.evaluateInFrame(assignment, frameIndex(frameID))
+}
+
+// setProperty sets a property with the debug handler disabled, since the
+// assignment may run setters or proxy traps.
+func (da *DebugAdapter) setProperty(obj *goja.Object, name string, val goja.Value) error {
+	defer da.swapDebugHandler(nil, false)()
+
+	return obj.Set(name, val)
+}
+
+// describeValue renders a value the way the Variables view shows it.
+func (da *DebugAdapter) describeValue(name string, val goja.Value) Variable {
+	v := Variable{
+		Name:  name,
+		Value: "undefined",
+		Type:  "undefined",
+	}
+
+	if val == nil || goja.IsUndefined(val) {
+		return v
+	}
+	if goja.IsNull(val) {
+		v.Value = "null"
+		v.Type = "null"
+		return v
+	}
+
+	v.Type = da.getValueType(val)
+
+	obj, ok := val.(*goja.Object)
+	if !ok {
+		v.Value = val.String()
+		return v
+	}
+
+	// Create reference for complex types. Their String() is not used, it
+	// would join whole arrays or run a toString() from the script.
+	if _, isFunc := goja.AssertFunction(obj); !isFunc {
+		da.varRefCounter++
+		v.VariablesReference = da.varRefCounter
+		da.varRefMap[v.VariablesReference] = val
+		v.Value = da.formatComplexValue(val)
+		v.IndexedVariables, v.NamedVariables = childCounts(obj)
+	} else {
+		v.Value = fmt.Sprintf("[Function: %s]", name)
+	}
+
+	return v
+}
+
+func (da *DebugAdapter) isPaused() bool {
+	da.debugStateMutex.Lock()
+	defer da.debugStateMutex.Unlock()
+	return da.waitingForCmd
+}
+
+func (da *DebugAdapter) sendErrorResponse(req *Request, err error) {
+	da.sendResponse(req.Seq, req.Command, false, map[string]string{
+		"error": err.Error(),
+	})
+}
+
+// frameIndex converts a DAP frame ID into an index into the call stack.
+func frameIndex(frameID int) int {
+	if frameID <= 0 {
+		return 0
+	}
+	return frameID - 1
+}
+
+// parseExpression parses src as a single JS expression.
+func parseExpression(src string) (ast.Expression, error) {
+	_, expr, err := parseExpressionProgram(src)
+	return expr, err
+}
+
+// parseExpressionProgram is parseExpression that also returns the program
+// the expression was parsed in, for reading back its source ranges.
+func parseExpressionProgram(src string) (*ast.Program, ast.Expression, error) {
+	prg, err := parser.ParseFile(nil, "", "("+src+"\n)", 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid expression '%s': %v", src, err)
+	}
+	if len(prg.Body) != 1 {
+		return nil, nil, fmt.Errorf("invalid expression '%s'", src)
+	}
+	stmt, ok := prg.Body[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid expression '%s'", src)
+	}
+	return prg, stmt.Expression, nil
+}
+
+// checkExpression makes sure a new value is a single expression, so it can
+// be wrapped in an assignment without changing its meaning.
+func checkExpression(src string) error {
+	_, err := parseExpression(src)
+	return err
+}
+
+func isIdentifier(name string) bool {
+	expr, err := parseExpression(name)
+	if err != nil {
+		return false
+	}
+	_, ok := expr.(*ast.Identifier)
+	return ok
+}
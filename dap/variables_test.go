@@ -0,0 +1,65 @@
+//go:build !gojaupstream
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSetVariable(t *testing.T) {
+	s := newTestSession(t, `var total = 1;
+var obj = { a: 1 };
+let scoped = 2;
+function show() {
+  let scoped = "inner";
+  console.log(scoped);
+}
+console.log(total);
+show();
+`)
+	s.setBreakpoints(SourceBreakpoint{Line: 8})
+	s.request("configurationDone", nil, nil)
+	s.stopped()
+
+	var obj EvaluateResponseBody
+	s.request("evaluate", EvaluateArguments{Expression: "obj", FrameID: 1, Context: "watch"}, &obj)
+	refs := s.scopes(1)
+	for _, set := range []struct {
+		args SetVariableArguments
+		want string
+	}{
+		{SetVariableArguments{VariablesReference: refs["Global"], Name: "total", Value: "40 + 2"}, "42"},
+		{SetVariableArguments{VariablesReference: obj.VariablesReference, Name: "a", Value: `"x"`}, "x"},
+		{SetVariableArguments{VariablesReference: refs["Script"], Name: "scoped", Value: "scoped * 10"}, "20"},
+	} {
+		var body SetVariableResponseBody
+		s.request("setVariable", set.args, &body)
+		if body.Value != set.want {
+			t.Errorf("setVariable %s = %s, want %s", set.args.Name, body.Value, set.want)
+		}
+	}
+	var body SetExpressionResponseBody
+	s.request("setExpression", SetExpressionArguments{Expression: "obj.b", Value: "obj.a + total", FrameID: 1}, &body)
+	if body.Value != "x42" {
+		t.Errorf("setExpression obj.b = %s, want x42", body.Value)
+	}
+	for expr, want := range map[string]string{"total": "42", "obj.a": "x", "scoped": "20", "obj.b": "x42"} {
+		if got := s.evaluate(expr); got != want {
+			t.Errorf("%s = %s after setting it, want %s", expr, got, want)
+		}
+	}
+
+	// In show() the script's scoped is hidden by the local one, which only
+	// builds that read frames can change
+	s.setBreakpoints(SourceBreakpoint{Line: 6})
+	s.resume()
+	s.stopped()
+	if msg := s.send("setVariable", SetVariableArguments{VariablesReference: s.scopes(1)["Script"], Name: "scoped", Value: "1"}); msg.Success {
+		t.Error("a shadowed script variable was set")
+	}
+	if msg := s.send("setExpression", SetExpressionArguments{Expression: "scoped", Value: "1", FrameID: 1}); msg.Success && !newFrameInspector().canReadFrames() {
+		t.Error("a local was set against the global scope")
+	}
+	s.resume()
+	s.terminated()
+}